@@ -13,15 +13,33 @@ type App struct {
 	browser playwright.Browser
 	ctx     playwright.BrowserContext
 
-	argService  *services.ArgsService
-	getService  *services.GetService
-	postService *services.PostService
+	argService      *services.ArgsService
+	getService      *services.GetService
+	postService     *services.PostService
+	formService     *services.FormService
+	storedService   *services.StoredService
+	crawlerService  *services.CrawlerService
+	identityService *services.IdentityService
+	headerService   *services.HeaderService
+	uploadService   *services.UploadService
+	importService   *services.ImportService
+	scopeService    *services.ScopeService
+	resourceService *services.ResourceService
 }
 
 func NewApp() *App {
 	var argService *services.ArgsService
 	var getService *services.GetService
 	var postService *services.PostService
+	var formService *services.FormService
+	var storedService *services.StoredService
+	var crawlerService *services.CrawlerService
+	var identityService *services.IdentityService
+	var headerService *services.HeaderService
+	var uploadService *services.UploadService
+	var importService *services.ImportService
+	var scopeService *services.ScopeService
+	var resourceService *services.ResourceService
 	var err error
 
 	if argService, err = services.GetArgsService(); err != nil {
@@ -36,10 +54,55 @@ func NewApp() *App {
 		utils.HandleErr(err)
 	}
 
+	if formService, err = services.GetFormService(); err != nil {
+		utils.HandleErr(err)
+	}
+
+	if storedService, err = services.GetStoredService(); err != nil {
+		utils.HandleErr(err)
+	}
+
+	if crawlerService, err = services.GetCrawlerService(); err != nil {
+		utils.HandleErr(err)
+	}
+
+	if identityService, err = services.GetIdentityService(); err != nil {
+		utils.HandleErr(err)
+	}
+
+	if headerService, err = services.GetHeaderService(); err != nil {
+		utils.HandleErr(err)
+	}
+
+	if uploadService, err = services.GetUploadService(); err != nil {
+		utils.HandleErr(err)
+	}
+
+	if importService, err = services.GetImportService(); err != nil {
+		utils.HandleErr(err)
+	}
+
+	if scopeService, err = services.GetScopeService(); err != nil {
+		utils.HandleErr(err)
+	}
+
+	if resourceService, err = services.GetResourceService(); err != nil {
+		utils.HandleErr(err)
+	}
+
 	return &App{
-		argService:  argService,
-		getService:  getService,
-		postService: postService,
+		argService:      argService,
+		getService:      getService,
+		postService:     postService,
+		formService:     formService,
+		storedService:   storedService,
+		crawlerService:  crawlerService,
+		identityService: identityService,
+		headerService:   headerService,
+		uploadService:   uploadService,
+		importService:   importService,
+		scopeService:    scopeService,
+		resourceService: resourceService,
 	}
 }
 
@@ -63,10 +126,22 @@ func (app *App) Start() error {
 		utils.Log.Info("Browser launched")
 	}
 
-	if ctx, err := app.browser.NewContext(); err != nil {
+	// every identity gets its own context, payloads are injected through the injector's one
+	if err := app.identityService.Run(app.browser); err != nil {
 		return err
 	} else {
-		app.ctx = ctx
+		app.ctx = app.identityService.Injector().Ctx
+	}
+
+	// logins are done by now, from here on every request of every identity goes through the scope rules
+	// and the resource blocklist
+	for _, identity := range app.identityService.Identities {
+		if err := app.scopeService.Apply(identity.Ctx); err != nil {
+			return err
+		}
+		if err := app.resourceService.Apply(identity.Ctx); err != nil {
+			return err
+		}
 	}
 
 	return nil
@@ -74,7 +149,7 @@ func (app *App) Start() error {
 
 func (app *App) Close() {
 	utils.Log.Info("Closing the app")
-	if err := app.ctx.Close(); err != nil {
+	if err := app.identityService.Close(); err != nil {
 		utils.HandleErr(err)
 	} else {
 		utils.Log.Info("Context closed")
@@ -99,15 +174,101 @@ func (app *App) Run() {
 	method, err := app.argService.Get(services.ArgKeys.Method)
 	utils.HandleErr(err)
 
-	if strings.ToUpper(method.(string)) == "GET" {
-		app.getService.Run()
-		err := app.getService.Scan(app.ctx)
+	runGet := strings.ToUpper(method.(string)) == "GET"
+	runPost := !runGet
+
+	// imported files mix GET targets with other requests, each kind goes to its own scan
+	if app.importService.Imported() {
+		err := app.importService.Run()
 		utils.HandleErr(err)
-	} else {
-		app.postService.Run()
-		err := app.postService.Scan(app.ctx)
+
+		runGet = len(app.importService.Urls) > 0
+		runPost = len(app.importService.Requests) > 0
+	}
+
+	seeds := []string{}
+
+	if runGet {
+		seeds = append(seeds, app.RunGet()...)
+	}
+
+	if runPost {
+		seeds = append(seeds, app.RunPost()...)
+	}
+
+	if len(app.storedService.VerifyUrls) > 0 {
+		err := app.storedService.Sweep()
 		utils.HandleErr(err)
 	}
 
+	if app.storedService.Crawl {
+		seeds = append(seeds, app.storedService.VerifyUrls...)
+		err := app.crawlerService.Sweep(seeds)
+		utils.HandleErr(err)
+	}
+
+	app.scopeService.Summary()
+	app.resourceService.Summary()
+
 	utils.Log.Success("Ran the app")
 }
+
+// RunGet scans the GET targets and their forms, returning the first scanned pages of every host as crawl seeds
+func (app *App) RunGet() []string {
+	app.getService.Run()
+	err := app.storedService.Run(app.getService.UUID)
+	utils.HandleErr(err)
+
+	// the sites of the targets and verification pages are first party, requests to any other one are third party
+	app.scopeService.AddSites(app.getService.Origins)
+	app.scopeService.AddSites(app.storedService.VerifyUrls)
+
+	// static -H headers go with every first party GET request, whichever identity makes it
+	for _, identity := range app.identityService.Identities {
+		err := app.headerService.Apply(identity.Ctx, app.getService.Origins)
+		utils.HandleErr(err)
+	}
+
+	err = app.getService.Scan(app.ctx)
+	utils.HandleErr(err)
+
+	if forms, err := app.argService.Get(services.ArgKeys.Forms); err == nil && forms.(bool) {
+		app.formService.Run(app.ctx, app.getService.EachUrl)
+		err := app.formService.Scan(app.ctx, app.getService.RawPayloads, app.getService.UUID)
+		utils.HandleErr(err)
+	}
+
+	return app.getService.Seeds
+}
+
+// RunPost scans the raw requests, returning their target URLs as crawl seeds
+func (app *App) RunPost() []string {
+	app.postService.Run()
+
+	// the canary ledger is already running when the GET scan went first
+	if !app.storedService.Started() {
+		err := app.storedService.Run(app.postService.UUID)
+		utils.HandleErr(err)
+	}
+
+	var seeds []string
+	for _, request := range app.postService.Requests {
+		seeds = append(seeds, app.postService.GetTargetUrl(request))
+	}
+	// uploaded files may be served from another site, it is visited on purpose too
+	app.scopeService.AddSites(seeds)
+	app.scopeService.AddSites(app.storedService.VerifyUrls)
+	if retrieve, err := app.argService.Get(services.ArgKeys.Retrieve); err == nil {
+		app.scopeService.AddSites([]string{retrieve.(string)})
+	}
+
+	err := app.postService.Scan(app.ctx)
+	utils.HandleErr(err)
+
+	if app.uploadService.Enabled() {
+		err := app.uploadService.Retrieve(app.ctx, app.postService.CombinedUrls, app.postService.UUID)
+		utils.HandleErr(err)
+	}
+
+	return seeds
+}
@@ -26,6 +26,37 @@ const (
 	keyCommand  ArgKey = "command"
 	keyDelay    ArgKey = "delay"
 	keyTimeout  ArgKey = "timeout"
+	keyForms    ArgKey = "forms"
+	keyVerify   ArgKey = "verify"
+	keyCrawl    ArgKey = "crawl"
+	keyDepth    ArgKey = "crawl-depth"
+	keyPages    ArgKey = "crawl-limit"
+	keyDeny     ArgKey = "crawl-deny"
+	keyIdentity ArgKey = "identity"
+	keyInjectAs ArgKey = "inject-as"
+	keyPath     ArgKey = "path"
+	keyDiscover ArgKey = "discover"
+	keyParams   ArgKey = "params"
+	keyNames    ArgKey = "param-names"
+	keyHpp      ArgKey = "hpp"
+	keyNested   ArgKey = "nested"
+	keyStrategy ArgKey = "strategy"
+	keyCombos   ArgKey = "max-combinations"
+	keyPoints   ArgKey = "point-payloads"
+	keyUpload   ArgKey = "upload"
+	keyRetrieve ArgKey = "upload-url"
+	keyFormat   ArgKey = "format"
+	keyHosts    ArgKey = "import-hosts"
+	keyServer   ArgKey = "server"
+	keyMethods  ArgKey = "import-methods"
+	keyShape    ArgKey = "same-shape"
+	keyInHosts  ArgKey = "include-host"
+	keyExHosts  ArgKey = "exclude-host"
+	keyInPaths  ArgKey = "include-path"
+	keyExPaths  ArgKey = "exclude-path"
+	keyThirdPty ArgKey = "block-third-party"
+	keyResource ArgKey = "block-resources"
+	keyBlockUrl ArgKey = "block-url"
 )
 
 // ArgKeys is a "named enum" collection for reference
@@ -43,6 +74,37 @@ var ArgKeys = struct {
 	Command  ArgKey
 	Delay    ArgKey
 	Timeout  ArgKey
+	Forms    ArgKey
+	Verify   ArgKey
+	Crawl    ArgKey
+	Depth    ArgKey
+	Pages    ArgKey
+	Deny     ArgKey
+	Identity ArgKey
+	InjectAs ArgKey
+	Path     ArgKey
+	Discover ArgKey
+	Params   ArgKey
+	Names    ArgKey
+	Hpp      ArgKey
+	Nested   ArgKey
+	Strategy ArgKey
+	Combos   ArgKey
+	Points   ArgKey
+	Upload   ArgKey
+	Retrieve ArgKey
+	Format   ArgKey
+	Hosts    ArgKey
+	Server   ArgKey
+	Methods  ArgKey
+	Shape    ArgKey
+	InHosts  ArgKey
+	ExHosts  ArgKey
+	InPaths  ArgKey
+	ExPaths  ArgKey
+	ThirdPty ArgKey
+	Resource ArgKey
+	BlockUrl ArgKey
 }{
 	Method:   keyMethod,
 	Protocol: keyProtocol,
@@ -57,6 +119,37 @@ var ArgKeys = struct {
 	Command:  keyCommand,
 	Delay:    keyDelay,
 	Timeout:  keyTimeout,
+	Forms:    keyForms,
+	Verify:   keyVerify,
+	Crawl:    keyCrawl,
+	Depth:    keyDepth,
+	Pages:    keyPages,
+	Deny:     keyDeny,
+	Identity: keyIdentity,
+	InjectAs: keyInjectAs,
+	Path:     keyPath,
+	Discover: keyDiscover,
+	Params:   keyParams,
+	Names:    keyNames,
+	Hpp:      keyHpp,
+	Nested:   keyNested,
+	Strategy: keyStrategy,
+	Combos:   keyCombos,
+	Points:   keyPoints,
+	Upload:   keyUpload,
+	Retrieve: keyRetrieve,
+	Format:   keyFormat,
+	Hosts:    keyHosts,
+	Server:   keyServer,
+	Methods:  keyMethods,
+	Shape:    keyShape,
+	InHosts:  keyInHosts,
+	ExHosts:  keyExHosts,
+	InPaths:  keyInPaths,
+	ExPaths:  keyExPaths,
+	ThirdPty: keyThirdPty,
+	Resource: keyResource,
+	BlockUrl: keyBlockUrl,
 }
 
 // ArgsService holds the map of arguments and provides methods to interact with them
@@ -89,18 +182,54 @@ func (as *ArgsService) Init() error {
 	method := parser.String("m", "method", &argparse.Options{Help: "HTTP method to use (GET, POST, PUT, etc.)", Default: "GET"})
 	protocol := parser.String("", "protocol", &argparse.Options{Help: "HTTP protocol to use (http/https)", Default: "https"})
 
-	urls := parser.String("u", "urls", &argparse.Options{Help: "URLs to scan for GET method, request for other methods", Default: "urls.txt"})
-	payloads := parser.String("p", "payloads", &argparse.Options{Help: "Payloads to use", Default: "payloads.txt"})
+	urls := parser.List("u", "urls", &argparse.Options{Help: "URL to scan or file of URLs for GET method, - reads the standard input, multiple allowed. For other methods a request file, a directory or glob of request files, or a file of requests separated by === lines", Default: []string{"urls.txt"}})
+	format := parser.Selector("f", "format", []string{FormatAuto, FormatUrls, FormatRequest, FormatHar, FormatOpenApi, FormatCurl, FormatBurp, FormatZap}, &argparse.Options{Help: "Format of the -u input: urls, request, har, openapi (OpenAPI 3 or Swagger 2, JSON or YAML), curl (a file of curl commands), burp (Burp Suite saved items XML) or zap (ZAP XML report), detected from the file when auto", Default: FormatAuto})
+	hosts := parser.List("", "import-hosts", &argparse.Options{Help: "Hosts to keep from imported files, *.example.com also matches subdomains, multiple allowed. All hosts when empty", Default: []string{}})
+	server := parser.String("", "server", &argparse.Options{Help: "Base URL for imported OpenAPI operations, replaces the scheme and host of the spec servers and completes relative ones"})
+	methods := parser.List("", "import-methods", &argparse.Options{Help: "Methods of the OpenAPI operations to import, multiple allowed. DELETE is left out unless given", Default: []string{"GET", "POST", "PUT", "PATCH"}})
+	shape := parser.Int("", "same-shape", &argparse.Options{Help: "Keep at most this many URLs of the same endpoint shape (host, path with IDs generalized and parameter names), 0 keeps all", Default: 0})
+	inHosts := parser.List("", "include-host", &argparse.Options{Help: "Regex of hosts in scope, targets, crawled links and browser requests to other hosts are dropped, multiple allowed", Default: []string{}})
+	exHosts := parser.List("", "exclude-host", &argparse.Options{Help: "Regex of hosts out of scope, multiple allowed", Default: []string{}})
+	inPaths := parser.List("", "include-path", &argparse.Options{Help: "Regex of paths in scope, multiple allowed", Default: []string{}})
+	exPaths := parser.List("", "exclude-path", &argparse.Options{Help: "Regex of paths out of scope, multiple allowed", Default: []string{}})
+	thirdParty := parser.Flag("", "block-third-party", &argparse.Options{Help: "Block browser requests to sites other than the ones of the targets, like analytics and ads", Default: false})
+	resources := parser.List("", "block-resources", &argparse.Options{Help: "Resource types the browser doesn't load to speed up the scan: image, media, font, stylesheet, texttrack, manifest or other, comma separated or multiple. Scripts and documents are always loaded", Default: []string{}})
+	blockUrls := parser.List("", "block-url", &argparse.Options{Help: "Regex of URLs the browser doesn't load, scripts and documents excepted, multiple allowed", Default: []string{}})
+	payloads := parser.String("p", "payloads", &argparse.Options{Help: "File of payloads to use, - reads the standard input", Default: "payloads.txt"})
 
 	threads := parser.Int("T", "threads", &argparse.Options{Help: "Number of threads to run scans in parallel", Default: 10})
 	continueFrom := parser.Int("c", "continue", &argparse.Options{Help: "Offset to continue scan from", Default: 0})
 
 	verbose := parser.String("v", "verbose", &argparse.Options{Help: "Log level (ALL, LOG, INFO, WARN, ERROR)", Default: "ALL"})
-	headers := parser.List("H", "header", &argparse.Options{Help: "Headers to append to GET request, multiple allowed", Default: []string{}})
+	headers := parser.List("H", "header", &argparse.Options{Help: "Headers to append to GET requests as \"Name: value\", multiple allowed. A {payload} marker makes the header, a Cookie header cookie, the User-Agent or the Referer an injection point", Default: []string{}})
 
 	timeout := parser.Int("t", "timeout", &argparse.Options{Help: "Timeout for requests in ms", Default: 5000})
 	delay := parser.Int("d", "delay", &argparse.Options{Help: "Delay between requests", Default: 0})
 
+	strategy := parser.Selector("", "strategy", []string{StrategySniper, StrategyBatteringRam, StrategyPitchfork, StrategyClusterBomb}, &argparse.Options{Help: "Attack strategy for named injection points like {p1} and {p2}: sniper, battering-ram, pitchfork or cluster-bomb", Default: StrategySniper})
+	combos := parser.Int("", "max-combinations", &argparse.Options{Help: "Maximum number of payload combinations per target generated by the attack strategy, 0 for no limit", Default: 10000})
+	points := parser.List("", "point-payloads", &argparse.Options{Help: "Payload file for a named injection point as p2=file.txt, multiple allowed. Other points use the main payloads", Default: []string{}})
+
+	upload := parser.Flag("", "upload", &argparse.Options{Help: "Replace the file of a multipart upload request with generated SVG, HTML, XML, GIF/JS polyglot and spoofed type payload files", Default: false})
+	retrieve := parser.String("", "upload-url", &argparse.Options{Help: "URL pattern of uploaded files, {filename} is replaced by the uploaded file name, to check how they are served"})
+
+	path := parser.Flag("", "path", &argparse.Options{Help: "Also inject payloads into every URL path segment and as a new trailing segment", Default: false})
+	pollution := parser.Flag("", "hpp", &argparse.Options{Help: "Also generate parameter pollution variants: duplicates, array and nested names, mixed separators", Default: false})
+	nested := parser.Flag("", "nested", &argparse.Options{Help: "Also inject into values nested inside parameters: JSON, base64 and base64url encoded data, URLs and form data", Default: false})
+	paramNames := parser.Flag("", "param-names", &argparse.Options{Help: "Also inject payloads as query parameter names with a benign value", Default: false})
+
+	discover := parser.Flag("", "discover", &argparse.Options{Help: "Discover unlisted parameters the pages reflect and inject into them too", Default: false})
+	params := parser.String("", "params", &argparse.Options{Help: "Wordlist of parameter names for discovery, a built in list is used when empty"})
+
+	verify := parser.String("V", "verify", &argparse.Options{Help: "File with verification URLs to visit after every injection, enables stored XSS mode"})
+	crawl := parser.Flag("", "crawl", &argparse.Options{Help: "Crawl the site after the scan and match every canary that fires or renders against the injections", Default: false})
+	depth := parser.Int("", "crawl-depth", &argparse.Options{Help: "Maximum link depth for the crawl sweep", Default: 3})
+	pages := parser.Int("", "crawl-limit", &argparse.Options{Help: "Maximum number of pages visited by the crawl sweep", Default: 200})
+	deny := parser.String("", "crawl-deny", &argparse.Options{Help: "Regex of links the crawl sweep never follows, matched against the path and query. The default leaves out links that change state", Default: DefaultCrawlDeny})
+	identities := parser.List("", "identity", &argparse.Options{Help: "Browser identity as name=state.json (storage state) or name=login.js (login script, first line `// <login url>`, then optionally `// success-url: <regex>` or `// success-selector: <css>` confirming the login), multiple allowed", Default: []string{}})
+	injectAs := parser.String("", "inject-as", &argparse.Options{Help: "Identity to inject payloads as, the others verify. Defaults to the first identity"})
+	forms := parser.Flag("", "forms", &argparse.Options{Help: "Discover forms on the scanned pages and scan their fields by submitting them in the browser", Default: false})
+
 	output := parser.String("o", "output", &argparse.Options{Help: "Output file"})
 	report := parser.String("r", "report", &argparse.Options{Help: "Report file"})
 
@@ -110,6 +239,17 @@ func (as *ArgsService) Init() error {
 		return err
 	}
 
+	// the standard input can only be read once
+	stdinReaders := 0
+	for _, source := range append(*urls, *payloads) {
+		if source == "-" {
+			stdinReaders++
+		}
+	}
+	if stdinReaders > 1 {
+		return errors.New("only one of -u and -p can read the standard input")
+	}
+
 	// Initialize the argsMap with parsed values
 	argsMap := make(map[ArgKey]interface{})
 	argsMap[ArgKeys.Command] = *command
@@ -121,6 +261,18 @@ func (as *ArgsService) Init() error {
 	if *command == "scan" {
 		// unique for scan command
 		argsMap[ArgKeys.Urls] = *urls
+		argsMap[ArgKeys.Format] = *format
+		argsMap[ArgKeys.Hosts] = *hosts
+		argsMap[ArgKeys.Server] = *server
+		argsMap[ArgKeys.Methods] = *methods
+		argsMap[ArgKeys.Shape] = *shape
+		argsMap[ArgKeys.InHosts] = *inHosts
+		argsMap[ArgKeys.ExHosts] = *exHosts
+		argsMap[ArgKeys.InPaths] = *inPaths
+		argsMap[ArgKeys.ExPaths] = *exPaths
+		argsMap[ArgKeys.ThirdPty] = *thirdParty
+		argsMap[ArgKeys.Resource] = *resources
+		argsMap[ArgKeys.BlockUrl] = *blockUrls
 		argsMap[ArgKeys.Payload] = *payloads
 		argsMap[ArgKeys.Verbose] = *verbose
 		argsMap[ArgKeys.Threads] = *threads
@@ -130,6 +282,25 @@ func (as *ArgsService) Init() error {
 		argsMap[ArgKeys.Continue] = *continueFrom
 		argsMap[ArgKeys.Delay] = *delay
 		argsMap[ArgKeys.Timeout] = *timeout
+		argsMap[ArgKeys.Forms] = *forms
+		argsMap[ArgKeys.Path] = *path
+		argsMap[ArgKeys.Names] = *paramNames
+		argsMap[ArgKeys.Hpp] = *pollution
+		argsMap[ArgKeys.Nested] = *nested
+		argsMap[ArgKeys.Strategy] = *strategy
+		argsMap[ArgKeys.Combos] = *combos
+		argsMap[ArgKeys.Points] = *points
+		argsMap[ArgKeys.Upload] = *upload
+		argsMap[ArgKeys.Retrieve] = *retrieve
+		argsMap[ArgKeys.Discover] = *discover
+		argsMap[ArgKeys.Params] = *params
+		argsMap[ArgKeys.Verify] = *verify
+		argsMap[ArgKeys.Crawl] = *crawl
+		argsMap[ArgKeys.Depth] = *depth
+		argsMap[ArgKeys.Pages] = *pages
+		argsMap[ArgKeys.Deny] = *deny
+		argsMap[ArgKeys.Identity] = *identities
+		argsMap[ArgKeys.InjectAs] = *injectAs
 	} else if *command == "report" {
 		// unique for report command
 		argsMap[ArgKeys.Report] = *report
@@ -0,0 +1,326 @@
+package services
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/textproto"
+	"net/url"
+	"strconv"
+	"strings"
+	"xss/utils"
+)
+
+// quoteEscaper escapes multipart header parameters the way mime/multipart does
+var quoteEscaper = strings.NewReplacer("\\", "\\\\", `"`, "\\\"")
+
+// formPart is a part of a multipart body
+type formPart struct {
+	Name        string
+	FileName    string
+	ContentType string
+	Content     []byte
+	Header      textproto.MIMEHeader
+}
+
+type BodyService struct {
+	jsonService *JsonService
+}
+
+var bodyServiceInstance *BodyService = nil
+
+// Singleton instance of BodyService
+func GetBodyService() (*BodyService, error) {
+	if bodyServiceInstance == nil {
+		jsonService, err := GetJsonService()
+		if err != nil {
+			return nil, err
+		}
+
+		bodyServiceInstance = &BodyService{
+			jsonService: jsonService,
+		}
+	}
+
+	return bodyServiceInstance, nil
+}
+
+// HeaderValue looks a header of a parsed request up case-insensitively
+func HeaderValue(request map[string]string, name string) (string, string) {
+	for key, value := range request {
+		if !strings.HasPrefix(key, "_ ") && strings.EqualFold(key, name) {
+			return key, value
+		}
+	}
+	return "", ""
+}
+
+// CombineBodyWithPayload injects every field of a request body without a {payload} marker,
+// one field at a time, picking the body format from the Content-Type header
+func (bs *BodyService) CombineBodyWithPayload(request map[string]string, payloads []string) []map[string]string {
+	_, contentType := HeaderValue(request, "Content-Type")
+	contentType = strings.ToLower(contentType)
+
+	switch {
+	case strings.Contains(contentType, "json"):
+		return bs.CombineJsonWithPayload(request, payloads)
+	case strings.Contains(contentType, "application/x-www-form-urlencoded"):
+		return bs.CombineFormWithPayload(request, payloads)
+	case strings.Contains(contentType, "multipart/form-data"):
+		return bs.CombineMultipartWithPayload(request, payloads)
+	}
+
+	return nil
+}
+
+// CombineJsonWithPayload replaces every string leaf of a JSON body with the payloads, the JSON pointer is the parameter.
+// Payloads go in raw, the JSON encoder escapes them so the body stays valid.
+func (bs *BodyService) CombineJsonWithPayload(request map[string]string, payloads []string) []map[string]string {
+	body := request["_ BODY"]
+
+	leaves, err := bs.jsonService.JsonStringLeaves(body)
+	if err != nil {
+		utils.Log.Warn("Request body is not valid JSON:", err)
+		return nil
+	}
+
+	var combinedRequests []map[string]string
+	for _, leaf := range leaves {
+		for _, payload := range payloads {
+			injected, err := bs.jsonService.JsonSetLeaf(body, leaf.Pointer, payload)
+			if err != nil {
+				continue
+			}
+
+			combinedRequests = append(combinedRequests, bs.WithBody(request, injected, "json", leaf.Pointer))
+		}
+	}
+
+	return combinedRequests
+}
+
+// CombineFormWithPayload replaces every value of a form-urlencoded body with the query escaped payloads
+func (bs *BodyService) CombineFormWithPayload(request map[string]string, payloads []string) []map[string]string {
+	pairs := strings.Split(strings.TrimSpace(request["_ BODY"]), "&")
+
+	var combinedRequests []map[string]string
+	for ix, pair := range pairs {
+		rawName, _, _ := strings.Cut(pair, "=")
+		if rawName == "" {
+			continue
+		}
+
+		name, err := url.QueryUnescape(rawName)
+		if err != nil {
+			name = rawName
+		}
+
+		for _, payload := range payloads {
+			injected := append([]string{}, pairs...)
+			injected[ix] = rawName + "=" + url.QueryEscape(payload)
+			combinedRequests = append(combinedRequests, bs.WithBody(request, strings.Join(injected, "&"), "body", name))
+		}
+	}
+
+	return combinedRequests
+}
+
+// CombineMultipartWithPayload injects into every field value of a multipart body, and into the filename
+// and content type of every file part. The body is rebuilt with a fresh boundary each time.
+func (bs *BodyService) CombineMultipartWithPayload(request map[string]string, payloads []string) []map[string]string {
+	_, contentType := HeaderValue(request, "Content-Type")
+
+	parts, err := bs.ParseMultipart(request["_ BODY"], contentType)
+	if err != nil {
+		utils.Log.Warn("Request body is not valid multipart:", err)
+		return nil
+	}
+
+	var combinedRequests []map[string]string
+	add := func(injected []formPart, location string, param string) {
+		body, contentType, err := bs.BuildMultipart(injected)
+		if err != nil {
+			utils.Log.Warn("Error building multipart body:", err)
+			return
+		}
+
+		newRequest := bs.WithBody(request, body, location, param)
+		key, _ := HeaderValue(newRequest, "Content-Type")
+		newRequest[key] = contentType
+		combinedRequests = append(combinedRequests, newRequest)
+	}
+
+	for ix, part := range parts {
+		for _, payload := range payloads {
+			injected := append([]formPart{}, parts...)
+
+			if part.FileName == "" {
+				injected[ix].Content = []byte(payload)
+				add(injected, "multipart", part.Name)
+				continue
+			}
+
+			injected[ix].FileName = payload
+			add(injected, "multipart-filename", part.Name)
+
+			injected = append([]formPart{}, parts...)
+			injected[ix].ContentType = payload
+			add(injected, "multipart-content-type", part.Name)
+		}
+	}
+
+	return combinedRequests
+}
+
+// EncodeValue encodes a value filled into a JSON string or a form-urlencoded body so the body stays valid,
+// other bodies take it as it is
+func (bs *BodyService) EncodeValue(request map[string]string, value string) string {
+	_, contentType := HeaderValue(request, "Content-Type")
+	contentType = strings.ToLower(contentType)
+
+	switch {
+	case strings.Contains(contentType, "json"):
+		encoded, err := bs.jsonService.encodeDocument(value)
+		if err != nil {
+			return value
+		}
+		return strings.TrimSuffix(strings.TrimPrefix(encoded, `"`), `"`)
+	case strings.Contains(contentType, "application/x-www-form-urlencoded"):
+		return url.QueryEscape(value)
+	}
+
+	return value
+}
+
+// ParseMultipart splits a multipart body into its parts using the boundary of the content type
+func (bs *BodyService) ParseMultipart(body string, contentType string) ([]formPart, error) {
+	_, params, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return nil, err
+	}
+	if params["boundary"] == "" {
+		return nil, fmt.Errorf("multipart content type without boundary")
+	}
+
+	// request files are often saved with bare LF line endings and lose the newline after the closing boundary
+	body = multipartLineEndings(body, params["boundary"])
+	body = strings.TrimRight(body, "\r\n") + "\r\n"
+
+	var parts []formPart
+	reader := multipart.NewReader(strings.NewReader(body), params["boundary"])
+	for {
+		part, err := reader.NextRawPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		content, err := io.ReadAll(part)
+		if err != nil {
+			return nil, err
+		}
+
+		parts = append(parts, formPart{
+			Name:        part.FormName(),
+			FileName:    part.FileName(),
+			ContentType: part.Header.Get("Content-Type"),
+			Content:     content,
+			Header:      part.Header,
+		})
+	}
+
+	return parts, nil
+}
+
+// multipartLineEndings ends the boundary lines, the part header lines and the newline before every boundary
+// with CRLF, the content of the parts keeps its own line endings
+func multipartLineEndings(body string, boundary string) string {
+	delimiter := "--" + boundary
+	isDelimiter := func(line string) bool {
+		line = strings.TrimSuffix(line, "\r")
+		return line == delimiter || line == delimiter+"--"
+	}
+
+	lines := strings.Split(body, "\n")
+	var builder strings.Builder
+	inHeaders := false
+	for ix, line := range lines {
+		last := ix == len(lines)-1
+		structural := inHeaders || isDelimiter(line)
+		if !last && isDelimiter(lines[ix+1]) {
+			structural = true
+		}
+
+		switch {
+		case isDelimiter(line):
+			inHeaders = true
+		case inHeaders && strings.TrimSuffix(line, "\r") == "":
+			inHeaders = false
+		}
+
+		if last {
+			builder.WriteString(line)
+		} else if structural {
+			builder.WriteString(strings.TrimSuffix(line, "\r") + "\r\n")
+		} else {
+			builder.WriteString(line + "\n")
+		}
+	}
+
+	return builder.String()
+}
+
+// BuildMultipart writes the parts into a body with a new boundary and returns it with its content type
+func (bs *BodyService) BuildMultipart(parts []formPart) (string, string, error) {
+	var buffer bytes.Buffer
+	writer := multipart.NewWriter(&buffer)
+
+	for _, part := range parts {
+		header := make(textproto.MIMEHeader)
+		for key, values := range part.Header {
+			header[key] = append([]string{}, values...)
+		}
+
+		disposition := fmt.Sprintf(`form-data; name="%s"`, quoteEscaper.Replace(part.Name))
+		if part.FileName != "" {
+			disposition += fmt.Sprintf(`; filename="%s"`, quoteEscaper.Replace(part.FileName))
+		}
+		header.Set("Content-Disposition", disposition)
+
+		if part.ContentType != "" {
+			header.Set("Content-Type", part.ContentType)
+		}
+
+		partWriter, err := writer.CreatePart(header)
+		if err != nil {
+			return "", "", err
+		}
+		if _, err := partWriter.Write(part.Content); err != nil {
+			return "", "", err
+		}
+	}
+
+	if err := writer.Close(); err != nil {
+		return "", "", err
+	}
+
+	return buffer.String(), writer.FormDataContentType(), nil
+}
+
+// WithBody clones the request with a new body and attribution, regenerating its Content-Length
+func (bs *BodyService) WithBody(request map[string]string, body string, location string, param string) map[string]string {
+	newRequest := utils.CloneMap(request)
+	newRequest["_ BODY"] = body
+	newRequest["_ LOCATION"] = location
+	newRequest["_ PARAM"] = param
+
+	if key, _ := HeaderValue(newRequest, "Content-Length"); key != "" {
+		newRequest[key] = strconv.Itoa(len(body))
+	}
+
+	return newRequest
+}
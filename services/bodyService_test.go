@@ -0,0 +1,117 @@
+package services
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseMultipart(t *testing.T) {
+	bs, err := GetBodyService()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	contentType := "multipart/form-data; boundary=XyZ"
+
+	tests := []struct {
+		name string
+		body string
+		want []formPart
+	}{
+		{
+			name: "bare LF line endings keep the newlines inside file parts",
+			body: "--XyZ\n" +
+				"Content-Disposition: form-data; name=\"title\"\n" +
+				"\n" +
+				"hello\n" +
+				"--XyZ\n" +
+				"Content-Disposition: form-data; name=\"file\"; filename=\"notes.txt\"\n" +
+				"Content-Type: text/plain\n" +
+				"\n" +
+				"line one\nline two\n\n" +
+				"--XyZ--\n",
+			want: []formPart{
+				{Name: "title", Content: []byte("hello")},
+				{Name: "file", FileName: "notes.txt", ContentType: "text/plain", Content: []byte("line one\nline two\n")},
+			},
+		},
+		{
+			name: "CRLF line endings with mixed newlines inside a file part",
+			body: "--XyZ\r\n" +
+				"Content-Disposition: form-data; name=\"file\"; filename=\"a.csv\"\r\n" +
+				"Content-Type: text/csv\r\n" +
+				"\r\n" +
+				"a,b\r\nc,d\ne,f\r\n" +
+				"--XyZ--\r\n",
+			want: []formPart{
+				{Name: "file", FileName: "a.csv", ContentType: "text/csv", Content: []byte("a,b\r\nc,d\ne,f")},
+			},
+		},
+		{
+			name: "closing boundary without a newline and a preamble",
+			body: "preamble\n" +
+				"--XyZ\n" +
+				"Content-Disposition: form-data; name=\"q\"\n" +
+				"\n" +
+				"1\n" +
+				"--XyZ--",
+			want: []formPart{
+				{Name: "q", Content: []byte("1")},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			parts, err := bs.ParseMultipart(test.body, contentType)
+			if err != nil {
+				t.Fatal(err)
+			}
+			comparePartContents(t, "ParseMultipart()", parts, test.want)
+
+			// building the parts and parsing them again gives the same parts
+			built, builtType, err := bs.BuildMultipart(parts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !strings.HasPrefix(builtType, "multipart/form-data; boundary=") {
+				t.Errorf("BuildMultipart() content type = %s", builtType)
+			}
+
+			reparsed, err := bs.ParseMultipart(built, builtType)
+			if err != nil {
+				t.Fatal(err)
+			}
+			comparePartContents(t, "round trip", reparsed, test.want)
+		})
+	}
+}
+
+func TestParseMultipartErrors(t *testing.T) {
+	bs, err := GetBodyService()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, contentType := range []string{"multipart/form-data", "multipart/form-data; boundary=\"unterminated"} {
+		if _, err := bs.ParseMultipart("--XyZ--\r\n", contentType); err == nil {
+			t.Errorf("ParseMultipart() with %q expected an error", contentType)
+		}
+	}
+}
+
+func comparePartContents(t *testing.T, name string, got []formPart, want []formPart) {
+	t.Helper()
+
+	if len(got) != len(want) {
+		t.Fatalf("%s returned %d parts, want %d", name, len(got), len(want))
+	}
+	for ix := range want {
+		if got[ix].Name != want[ix].Name || got[ix].FileName != want[ix].FileName || got[ix].ContentType != want[ix].ContentType ||
+			string(got[ix].Content) != string(want[ix].Content) {
+			t.Errorf("%s part %d = {%s %s %s %q}, want {%s %s %s %q}", name, ix,
+				got[ix].Name, got[ix].FileName, got[ix].ContentType, got[ix].Content,
+				want[ix].Name, want[ix].FileName, want[ix].ContentType, want[ix].Content)
+		}
+	}
+}
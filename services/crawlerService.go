@@ -0,0 +1,283 @@
+package services
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+	"xss/utils"
+
+	"github.com/playwright-community/playwright-go"
+)
+
+// linksScript collects everything on the page the crawler can follow
+const linksScript = `() => Array.from(document.querySelectorAll('a[href], area[href], iframe[src], frame[src], form[action]'))
+	.map((el) => el.href || el.src || el.action)
+	.filter((link) => typeof link === 'string' && link.length > 0)`
+
+// sessionEndingLinks are never followed, the sweep has to stay logged in
+var sessionEndingLinks = regexp.MustCompile(`(?i)log-?out|log-?off|sign-?out`)
+
+// DefaultCrawlDeny leaves out links that change state when a verifier follows them, like delete or approve
+// actions and action style query parameters
+const DefaultCrawlDeny = `(?i)(^|[/_.?&=-])(delete|remove|destroy|drop|purge|approve|reject|deny|cancel|revoke|disable|deactivate|unsubscribe|reset|archive|ban)([/_.?&=-]|$)|[?&](action|do|cmd|op)=`
+
+type CrawlerService struct {
+	argService      *ArgsService
+	monitorService  *MonitorService
+	ledgerService   *LedgerService
+	storedService   *StoredService
+	reportService   *ReportService
+	identityService *IdentityService
+	scopeService    *ScopeService
+
+	deny *regexp.Regexp
+}
+
+var crawlerServiceInstance *CrawlerService = nil
+
+// Singleton instance of CrawlerService
+func GetCrawlerService() (*CrawlerService, error) {
+	if crawlerServiceInstance == nil {
+		var argService *ArgsService
+		var monitorService *MonitorService
+		var ledgerService *LedgerService
+		var storedService *StoredService
+		var reportService *ReportService
+		var identityService *IdentityService
+		var scopeService *ScopeService
+		var err error
+
+		if argService, err = GetArgsService(); err != nil {
+			return nil, err
+		}
+
+		if monitorService, err = GetMonitorService(); err != nil {
+			return nil, err
+		}
+
+		if ledgerService, err = GetLedgerService(); err != nil {
+			return nil, err
+		}
+
+		if storedService, err = GetStoredService(); err != nil {
+			return nil, err
+		}
+
+		if reportService, err = GetReportService(); err != nil {
+			return nil, err
+		}
+
+		if identityService, err = GetIdentityService(); err != nil {
+			return nil, err
+		}
+
+		if scopeService, err = GetScopeService(); err != nil {
+			return nil, err
+		}
+
+		var deny *regexp.Regexp
+		if pattern, _ := argService.GetAll()[ArgKeys.Deny].(string); pattern != "" {
+			if deny, err = regexp.Compile(pattern); err != nil {
+				return nil, fmt.Errorf("invalid --%s pattern %q: %w", ArgKeys.Deny, pattern, err)
+			}
+		}
+
+		crawlerServiceInstance = &CrawlerService{
+			argService:      argService,
+			monitorService:  monitorService,
+			ledgerService:   ledgerService,
+			storedService:   storedService,
+			reportService:   reportService,
+			identityService: identityService,
+			scopeService:    scopeService,
+			deny:            deny,
+		}
+	}
+
+	return crawlerServiceInstance, nil
+}
+
+// Sweep crawls the site as every verifying identity and matches every canary that fires or renders
+// against the injection ledger, this is where payloads stored by one module show up in another
+func (cs *CrawlerService) Sweep(seeds []string) error {
+	options := cs.argService.GetAll()
+
+	found := 0
+	for _, identity := range cs.identityService.Verifiers() {
+		found += cs.Crawl(identity, seeds)
+	}
+
+	if found > 0 {
+		utils.Log.Success(fmt.Sprintf("Second-order XSS found while crawling: %d", found))
+	} else {
+		utils.Log.Info("No second-order XSS found while crawling")
+	}
+
+	if options[ArgKeys.Report].(string) != "" {
+		return cs.reportService.SaveReport(options[ArgKeys.Report].(string))
+	}
+
+	return nil
+}
+
+// Crawl walks the hosts of the seed URLs breadth first as the given identity, returns how many new findings it made
+func (cs *CrawlerService) Crawl(identity Identity, seeds []string) int {
+	options := cs.argService.GetAll()
+	maxDepth := options[ArgKeys.Depth].(int)
+	limit := options[ArgKeys.Pages].(int)
+	threads := max(1, options[ArgKeys.Threads].(int))
+
+	hosts := make(map[string]struct{})
+	visited := make(map[string]struct{})
+	var queue []string
+
+	for _, seed := range seeds {
+		parsed, err := url.Parse(StripInjectionPoints(seed))
+		if err != nil || parsed.Host == "" {
+			continue
+		}
+		hosts[parsed.Host] = struct{}{}
+
+		root := &url.URL{Scheme: parsed.Scheme, Host: parsed.Host, Path: "/"}
+		for _, link := range []string{root.String(), parsed.String()} {
+			if normalized, ok := cs.normalize(link, hosts); ok {
+				if _, exists := visited[normalized]; !exists {
+					visited[normalized] = struct{}{}
+					queue = append(queue, normalized)
+				}
+			}
+		}
+	}
+
+	utils.Log.Info(fmt.Sprintf("Crawling [%d] hosts for [%d] injections, depth %d, limit %d pages", len(hosts), cs.ledgerService.Len(), maxDepth, limit))
+	if identity.Name != "" {
+		utils.Log.Info(fmt.Sprintf("Crawling as identity: %s", identity.Name))
+	}
+
+	found := 0
+	pagesVisited := 0
+	var m sync.Mutex
+
+	for depth := 0; depth <= maxDepth && len(queue) > 0 && pagesVisited < limit; depth++ {
+		var next []string
+		var wg sync.WaitGroup
+		slots := make(chan struct{}, threads)
+
+		for _, pageUrl := range queue {
+			if pagesVisited >= limit {
+				break
+			}
+			pagesVisited++
+
+			slots <- struct{}{}
+			wg.Add(1)
+			go func(pageUrl string) {
+				defer wg.Done()
+				defer func() { <-slots }()
+
+				hits, links, err := cs.Visit(identity.Ctx, pageUrl)
+				if err != nil {
+					utils.Log.Error(fmt.Sprintf("Error crawling %s: %s", pageUrl, err))
+					return
+				}
+
+				newFindings := cs.storedService.Record(hits, pageUrl, identity.Name)
+
+				m.Lock()
+				defer m.Unlock()
+
+				found += newFindings
+				for _, link := range links {
+					if normalized, ok := cs.normalize(link, hosts); ok {
+						if _, exists := visited[normalized]; !exists {
+							visited[normalized] = struct{}{}
+							next = append(next, normalized)
+						}
+					}
+				}
+			}(pageUrl)
+		}
+
+		wg.Wait()
+		utils.Log.Info(fmt.Sprintf("Crawl depth %d done, pages visited: %d, queued: %d", depth, pagesVisited, len(next)))
+		queue = next
+	}
+
+	return found
+}
+
+// Visit loads a page with the monitors on and returns what fired, canaries rendered in the DOM and the links found
+func (cs *CrawlerService) Visit(ctx playwright.BrowserContext, pageUrl string) ([]MonitorHit, []string, error) {
+	options := cs.argService.GetAll()
+	timeout := float64(options[ArgKeys.Timeout].(int))
+
+	page, err := ctx.NewPage()
+	if err != nil {
+		return nil, nil, err
+	}
+	defer page.Close()
+
+	monitor, err := cs.monitorService.Attach(page, cs.ledgerService.Marker())
+	if err != nil {
+		return nil, nil, err
+	}
+	defer cs.monitorService.Detach(monitor)
+
+	if _, err := page.Goto(pageUrl, playwright.PageGotoOptions{Timeout: playwright.Float(timeout)}); err != nil {
+		return nil, nil, err
+	}
+
+	hits := cs.monitorService.Wait(monitor, time.Duration(1000)*time.Millisecond)
+
+	// a canary that renders without executing is still worth reporting, the context may just need another payload
+	if html, err := page.Evaluate(`() => document.documentElement.outerHTML`); err == nil {
+		if content, ok := html.(string); ok && strings.Contains(content, cs.ledgerService.Marker()) {
+			hits = append(hits, MonitorHit{Kind: "dom", Value: content, Sink: true})
+		}
+	}
+
+	var links []string
+	if result, err := page.Evaluate(linksScript); err == nil {
+		if entries, ok := result.([]interface{}); ok {
+			for _, entry := range entries {
+				if link, ok := entry.(string); ok {
+					links = append(links, link)
+				}
+			}
+		}
+	}
+
+	return hits, links, nil
+}
+
+// normalize drops fragments, logout links, links the deny pattern matches and anything outside the crawled
+// hosts or the scope rules
+func (cs *CrawlerService) normalize(link string, hosts map[string]struct{}) (string, bool) {
+	parsed, err := url.Parse(link)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") {
+		return "", false
+	}
+
+	if !cs.scopeService.InScope(link) {
+		return "", false
+	}
+
+	if sessionEndingLinks.MatchString(parsed.Path) {
+		return "", false
+	}
+
+	if cs.deny != nil && cs.deny.MatchString(parsed.RequestURI()) {
+		return "", false
+	}
+
+	if _, ok := hosts[parsed.Host]; !ok {
+		return "", false
+	}
+
+	parsed.Fragment = ""
+	parsed.RawFragment = ""
+	return parsed.String(), true
+}
@@ -0,0 +1,475 @@
+package services
+
+import (
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// curlValueOptions are the curl options the importer reads that take a value, short and long forms
+var curlValueOptions = map[string]string{
+	"-X": "--request", "-H": "--header", "-b": "--cookie", "-d": "--data", "-F": "--form", "-u": "--user",
+	"-A": "--user-agent", "-e": "--referer",
+	"--request": "--request", "--header": "--header", "--cookie": "--cookie", "--data": "--data",
+	"--data-ascii": "--data", "--data-binary": "--data-binary", "--data-raw": "--data-raw",
+	"--data-urlencode": "--data-urlencode", "--json": "--json", "--form": "--form", "--form-string": "--form-string",
+	"--user": "--user", "--user-agent": "--user-agent", "--referer": "--referer", "--url": "--url",
+	"--oauth2-bearer": "--oauth2-bearer",
+}
+
+// curlIgnoredValueOptions take a value that does not change the request, the value is skipped with them
+var curlIgnoredValueOptions = map[string]struct{}{
+	"-o": {}, "-m": {}, "-x": {}, "-U": {}, "-w": {}, "-c": {}, "-E": {}, "-T": {}, "-r": {}, "-y": {}, "-Y": {},
+	"-z": {}, "-K": {}, "-D": {}, "-C": {}, "-t": {}, "-Q": {}, "-P": {},
+	"--output": {}, "--max-time": {}, "--connect-timeout": {}, "--proxy": {}, "--proxy-user": {}, "--write-out": {},
+	"--retry": {}, "--retry-delay": {}, "--retry-max-time": {}, "--resolve": {}, "--connect-to": {},
+	"--cookie-jar": {}, "--cacert": {}, "--capath": {}, "--cert": {}, "--cert-type": {}, "--key": {},
+	"--key-type": {}, "--pass": {}, "--ciphers": {}, "--upload-file": {}, "--limit-rate": {}, "--range": {},
+	"--speed-limit": {}, "--speed-time": {}, "--time-cond": {}, "--config": {}, "--dump-header": {},
+	"--max-redirs": {}, "--interface": {}, "--dns-servers": {}, "--trace": {}, "--trace-ascii": {},
+	"--stderr": {}, "--proto": {}, "--proto-redir": {}, "--local-port": {}, "--expect100-timeout": {},
+	"--keepalive-time": {}, "--continue-at": {}, "--telnet-option": {}, "--quote": {}, "--ftp-port": {},
+	"--aws-sigv4": {}, "--unix-socket": {}, "--abstract-unix-socket": {}, "--max-filesize": {},
+}
+
+// curlCommand is a curl command line reduced to the parts of the request it sends
+type curlCommand struct {
+	Method     string
+	Url        string
+	Headers    []string
+	Cookies    []string
+	Data       []string
+	Parts      []formPart
+	Get        bool
+	Head       bool
+	Json       bool
+	Compressed bool
+	User       string
+	Bearer     string
+
+	// Dir is the directory @file and <file paths are relative to, the one of the curl file
+	Dir string
+}
+
+type CurlService struct {
+	fileService *FileService
+}
+
+var curlServiceInstance *CurlService = nil
+
+// Singleton instance of CurlService
+func GetCurlService() (*CurlService, error) {
+	if curlServiceInstance == nil {
+		fileService, err := GetFileService()
+		if err != nil {
+			return nil, err
+		}
+
+		curlServiceInstance = &CurlService{
+			fileService: fileService,
+		}
+	}
+
+	return curlServiceInstance, nil
+}
+
+// IsCurl reports whether the first command of the content is a curl command
+func (cs *CurlService) IsCurl(content string) bool {
+	for _, line := range strings.Split(content, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		return line == "curl" || strings.HasPrefix(line, "curl ") || strings.HasPrefix(line, "curl.exe ")
+	}
+	return false
+}
+
+// Commands splits the content into the arguments of each curl command with the quoting rules of bash:
+// single quotes, double quotes, $'...' strings, backslash escapes and line continuations.
+// Commands end at unquoted newlines, lines that are not curl commands are skipped.
+func (cs *CurlService) Commands(content string) ([][]string, error) {
+	var commands [][]string
+	var command []string
+	var token strings.Builder
+	inToken := false
+	line := 1
+
+	endToken := func() {
+		if inToken {
+			command = append(command, token.String())
+			token.Reset()
+			inToken = false
+		}
+	}
+	endCommand := func() {
+		endToken()
+		if len(command) > 0 && (command[0] == "curl" || command[0] == "curl.exe") {
+			commands = append(commands, command[1:])
+		}
+		command = nil
+	}
+
+	for ix := 0; ix < len(content); ix++ {
+		char := content[ix]
+
+		switch {
+		case char == '\n':
+			endCommand()
+			line++
+
+		case char == ' ' || char == '\t' || char == '\r':
+			endToken()
+
+		case char == '#' && !inToken:
+			for ix+1 < len(content) && content[ix+1] != '\n' {
+				ix++
+			}
+
+		case char == '\\':
+			if ix+1 >= len(content) {
+				break
+			}
+			ix++
+			if content[ix] == '\r' && ix+1 < len(content) && content[ix+1] == '\n' {
+				ix++
+			}
+			if content[ix] == '\n' {
+				// line continuation
+				line++
+				break
+			}
+			token.WriteByte(content[ix])
+			inToken = true
+
+		case char == '\'':
+			end := strings.IndexByte(content[ix+1:], '\'')
+			if end < 0 {
+				return nil, fmt.Errorf("line %d: unterminated single quote", line)
+			}
+			quoted := content[ix+1 : ix+1+end]
+			line += strings.Count(quoted, "\n")
+			token.WriteString(quoted)
+			inToken = true
+			ix += end + 1
+
+		case char == '$' && ix+1 < len(content) && content[ix+1] == '\'':
+			end, err := cs.ansiQuoted(content[ix+2:], &token)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", line, err)
+			}
+			line += strings.Count(content[ix+2:ix+2+end], "\n")
+			inToken = true
+			ix += end + 2
+
+		case char == '"':
+			ix++
+			for ; ix < len(content) && content[ix] != '"'; ix++ {
+				if content[ix] == '\n' {
+					line++
+				}
+				if content[ix] == '\\' && ix+1 < len(content) && strings.IndexByte("$`\"\\\n", content[ix+1]) >= 0 {
+					ix++
+					if content[ix] == '\n' {
+						line++
+						continue
+					}
+				}
+				token.WriteByte(content[ix])
+			}
+			if ix >= len(content) {
+				return nil, fmt.Errorf("line %d: unterminated double quote", line)
+			}
+			inToken = true
+
+		default:
+			token.WriteByte(char)
+			inToken = true
+		}
+	}
+	endCommand()
+
+	return commands, nil
+}
+
+// ansiQuoted decodes the body of a $'...' string into the token and returns the index of its closing quote
+func (cs *CurlService) ansiQuoted(content string, token *strings.Builder) (int, error) {
+	escapes := map[byte]string{'n': "\n", 't': "\t", 'r': "\r", 'a': "\a", 'b': "\b", 'e': "\x1b", 'E': "\x1b",
+		'f': "\f", 'v': "\v", '\\': "\\", '\'': "'", '"': "\"", '?': "?"}
+
+	for ix := 0; ix < len(content); ix++ {
+		char := content[ix]
+		if char == '\'' {
+			return ix, nil
+		}
+		if char != '\\' || ix+1 >= len(content) {
+			token.WriteByte(char)
+			continue
+		}
+
+		ix++
+		escape := content[ix]
+		if replacement, ok := escapes[escape]; ok {
+			token.WriteString(replacement)
+			continue
+		}
+
+		// numeric escapes: \xHH, \uHHHH, \UHHHHHHHH and octal \NNN
+		digits, base, size := "", 16, 0
+		switch escape {
+		case 'x':
+			size = 2
+		case 'u':
+			size = 4
+		case 'U':
+			size = 8
+		default:
+			if escape >= '0' && escape <= '7' {
+				base, size = 8, 3
+				ix--
+			}
+		}
+		for size > 0 && ix+1 < len(content) && len(digits) < size {
+			if _, err := strconv.ParseUint(content[ix+1:ix+2], base, 8); err != nil {
+				break
+			}
+			ix++
+			digits += string(content[ix])
+		}
+		if digits == "" {
+			token.WriteByte('\\')
+			token.WriteByte(escape)
+			continue
+		}
+
+		value, _ := strconv.ParseUint(digits, base, 32)
+		if escape == 'u' || escape == 'U' {
+			token.WriteString(string(rune(value)))
+		} else {
+			token.WriteByte(byte(value))
+		}
+	}
+
+	return 0, fmt.Errorf("unterminated $' quote")
+}
+
+// Parse reads the options of a curl command that shape the request, options about the transfer are skipped.
+// Files the options read are relative to dir, the working directory when empty.
+func (cs *CurlService) Parse(args []string, dir string) (*curlCommand, error) {
+	command := &curlCommand{Dir: dir}
+
+	for ix := 0; ix < len(args); ix++ {
+		arg := args[ix]
+
+		if !strings.HasPrefix(arg, "-") || arg == "-" {
+			command.Url = arg
+			continue
+		}
+
+		// short flags can be grouped as -sSL, the last one may take a value like -sX POST or -XPOST
+		option, value, hasValue := arg, "", false
+		if !strings.HasPrefix(arg, "--") && len(arg) > 2 {
+			for pos := 1; pos < len(arg); pos++ {
+				short := "-" + string(arg[pos])
+				_, known := curlValueOptions[short]
+				_, ignored := curlIgnoredValueOptions[short]
+				if known || ignored {
+					option, value, hasValue = short, arg[pos+1:], pos+1 < len(arg)
+					break
+				}
+				cs.flag(command, short)
+				option = ""
+			}
+			if option == "" {
+				continue
+			}
+		}
+
+		_, ignored := curlIgnoredValueOptions[option]
+		name, known := curlValueOptions[option]
+		if !known && !ignored {
+			cs.flag(command, option)
+			continue
+		}
+
+		if !hasValue {
+			if ix+1 >= len(args) {
+				return nil, fmt.Errorf("option %s without a value", option)
+			}
+			ix++
+			value = args[ix]
+		}
+		if ignored {
+			continue
+		}
+
+		if err := cs.option(command, name, value); err != nil {
+			return nil, fmt.Errorf("%s %s: %w", option, value, err)
+		}
+	}
+
+	if command.Url == "" {
+		return nil, fmt.Errorf("curl command without a URL")
+	}
+	return command, nil
+}
+
+// flag applies an option without a value, the ones that don't change the request are ignored
+func (cs *CurlService) flag(command *curlCommand, option string) {
+	switch option {
+	case "-G", "--get":
+		command.Get = true
+	case "-I", "--head":
+		command.Head = true
+	case "--compressed":
+		command.Compressed = true
+	}
+}
+
+// option applies an option with its value
+func (cs *CurlService) option(command *curlCommand, name string, value string) error {
+	switch name {
+	case "--request":
+		command.Method = strings.ToUpper(value)
+	case "--url":
+		command.Url = value
+	case "--header":
+		command.Headers = append(command.Headers, value)
+	case "--user-agent":
+		command.Headers = append(command.Headers, "User-Agent: "+value)
+	case "--referer":
+		command.Headers = append(command.Headers, "Referer: "+strings.TrimSuffix(value, ";auto"))
+	case "--user":
+		command.User = value
+	case "--oauth2-bearer":
+		command.Bearer = value
+	case "--cookie":
+		if !strings.Contains(value, "=") {
+			// without a = the value is a cookie file, its cookies are not sent as given
+			return fmt.Errorf("cookie files are not supported")
+		}
+		command.Cookies = append(command.Cookies, value)
+
+	case "--data", "--data-binary", "--json":
+		data := value
+		if strings.HasPrefix(value, "@") {
+			content, err := cs.fileService.ReadFileAsString(cs.path(command.Dir, value[1:]))
+			if err != nil {
+				return err
+			}
+			data = content
+			if name == "--data" {
+				// -d drops the line breaks of a file, --data-binary keeps them
+				data = strings.NewReplacer("\r", "", "\n", "").Replace(data)
+			}
+		}
+		command.Data = append(command.Data, data)
+		command.Json = command.Json || name == "--json"
+	case "--data-raw":
+		command.Data = append(command.Data, value)
+	case "--data-urlencode":
+		data, err := cs.urlencode(value, command.Dir)
+		if err != nil {
+			return err
+		}
+		command.Data = append(command.Data, data)
+
+	case "--form", "--form-string":
+		part, err := cs.formPart(value, name == "--form-string", command.Dir)
+		if err != nil {
+			return err
+		}
+		command.Parts = append(command.Parts, part)
+	}
+
+	return nil
+}
+
+// urlencode encodes a --data-urlencode value: content, =content, name=content, @file or name@file
+func (cs *CurlService) urlencode(value string, dir string) (string, error) {
+	separator := strings.IndexAny(value, "=@")
+	if separator < 0 {
+		return url.QueryEscape(value), nil
+	}
+
+	name, content := value[:separator], value[separator+1:]
+	if value[separator] == '@' {
+		fileContent, err := cs.fileService.ReadFileAsString(cs.path(dir, content))
+		if err != nil {
+			return "", err
+		}
+		content = fileContent
+	}
+
+	if name == "" {
+		return url.QueryEscape(content), nil
+	}
+	return name + "=" + url.QueryEscape(content), nil
+}
+
+// formPart reads a -F value: name=value, name=@file to upload a file or name=<file to send its content,
+// followed by ;type= and ;filename= settings
+func (cs *CurlService) formPart(value string, literal bool, dir string) (formPart, error) {
+	name, content, ok := strings.Cut(value, "=")
+	if !ok {
+		return formPart{}, fmt.Errorf("expected name=content")
+	}
+	part := formPart{Name: name}
+
+	if literal {
+		part.Content = []byte(content)
+		return part, nil
+	}
+
+	// the settings follow the value or the file name, a quoted value keeps its semicolons
+	settings := strings.Split(content, ";")
+	if end := strings.Index(content[min(1, len(content)):], `"`); strings.HasPrefix(content, `"`) && end >= 0 {
+		settings = strings.Split(content[end+2:], ";")
+		settings[0] = content[1 : end+1]
+	}
+	content = settings[0]
+	for _, setting := range settings[1:] {
+		key, settingValue, _ := strings.Cut(strings.TrimSpace(setting), "=")
+		switch strings.ToLower(key) {
+		case "type":
+			part.ContentType = settingValue
+		case "filename":
+			part.FileName = strings.Trim(settingValue, `"`)
+		}
+	}
+
+	switch {
+	case strings.HasPrefix(content, "@"):
+		fileContent, err := cs.fileService.ReadFileAsBytes(cs.path(dir, content[1:]))
+		if err != nil {
+			return formPart{}, err
+		}
+		part.Content = fileContent
+		if part.FileName == "" {
+			part.FileName = filepath.Base(content[1:])
+		}
+		if part.ContentType == "" {
+			part.ContentType = "application/octet-stream"
+		}
+	case strings.HasPrefix(content, "<"):
+		fileContent, err := cs.fileService.ReadFileAsBytes(cs.path(dir, content[1:]))
+		if err != nil {
+			return formPart{}, err
+		}
+		part.Content = fileContent
+	default:
+		part.Content = []byte(content)
+	}
+
+	return part, nil
+}
+
+// path resolves a file an option reads against the directory of the curl file, - stays the standard input
+func (cs *CurlService) path(dir string, path string) string {
+	if dir == "" || path == StdinPath || filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(dir, path)
+}
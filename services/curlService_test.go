@@ -0,0 +1,216 @@
+package services
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestCurlCommands(t *testing.T) {
+	cs, err := GetCurlService()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		content string
+		want    [][]string
+	}{
+		{
+			name: "chrome bash with $'' body",
+			content: `curl 'https://example.com/api/items?id=1' \
+  -H 'accept: application/json, text/plain, */*' \
+  -H 'content-type: application/json' \
+  -b 'sid=abc123; theme=dark' \
+  -H 'user-agent: Mozilla/5.0 (X11; Linux x86_64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/126.0.0.0 Safari/537.36' \
+  --data-raw $'{"name":"it\'s","note":"line\\nbreak","emoji":"\u00e9"}'
+`,
+			want: [][]string{{
+				"https://example.com/api/items?id=1",
+				"-H", "accept: application/json, text/plain, */*",
+				"-H", "content-type: application/json",
+				"-b", "sid=abc123; theme=dark",
+				"-H", "user-agent: Mozilla/5.0 (X11; Linux x86_64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/126.0.0.0 Safari/537.36",
+				"--data-raw", `{"name":"it's","note":"line\nbreak","emoji":"é"}`,
+			}},
+		},
+		{
+			name:    "firefox single line",
+			content: `curl 'https://example.com/login' --compressed -X POST -H 'User-Agent: Mozilla/5.0 (X11; Linux x86_64; rv:128.0) Gecko/20100101 Firefox/128.0' -H 'Accept: */*' -H 'Content-Type: application/x-www-form-urlencoded' -H 'Origin: https://example.com' -H 'Connection: keep-alive' --data-raw 'user=admin&pass=p%40ss'`,
+			want: [][]string{{
+				"https://example.com/login", "--compressed", "-X", "POST",
+				"-H", "User-Agent: Mozilla/5.0 (X11; Linux x86_64; rv:128.0) Gecko/20100101 Firefox/128.0",
+				"-H", "Accept: */*",
+				"-H", "Content-Type: application/x-www-form-urlencoded",
+				"-H", "Origin: https://example.com",
+				"-H", "Connection: keep-alive",
+				"--data-raw", "user=admin&pass=p%40ss",
+			}},
+		},
+		{
+			name: "burp copy as curl",
+			content: `curl -i -s -k -X $'POST' \
+    -H $'Host: example.com' -H $'Content-Type: application/json' -H $'Content-Length: 27' \
+    -b $'session=xyz; pref=a' \
+    --data-binary $'{\"q\":\"test\",\"tab\":\"\\t\"}' \
+    $'https://example.com/search'
+`,
+			want: [][]string{{
+				"-i", "-s", "-k", "-X", "POST",
+				"-H", "Host: example.com", "-H", "Content-Type: application/json", "-H", "Content-Length: 27",
+				"-b", "session=xyz; pref=a",
+				"--data-binary", `{"q":"test","tab":"\t"}`,
+				"https://example.com/search",
+			}},
+		},
+		{
+			name:    "$'' escapes",
+			content: `curl $'https://example.com/\x41\u00e9\101' -d $'a\tb\r\nc\'d\\e\"f'`,
+			want:    [][]string{{"https://example.com/Aé" + "A", "-d", "a\tb\r\nc'd\\e\"f"}},
+		},
+		{
+			name: "double quotes, escapes, comments and several commands",
+			content: `# exported requests
+curl "https://example.com/a?x=\"1\"&y=\$HOME" -H "X-Test: a\\b"
+
+curl https://example.com/b\ c \
+  -d q=1 # trailing comment
+`,
+			want: [][]string{
+				{`https://example.com/a?x="1"&y=$HOME`, "-H", `X-Test: a\b`},
+				{"https://example.com/b c", "-d", "q=1"},
+			},
+		},
+		{
+			name:    "grouped short flags",
+			content: `curl -sSLX POST https://example.com/ -d a=1`,
+			want:    [][]string{{"-sSLX", "POST", "https://example.com/", "-d", "a=1"}},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := cs.Commands(test.content)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("Commands() =\n%q\nwant\n%q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestCurlCommandsErrors(t *testing.T) {
+	cs, err := GetCurlService()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, content := range []string{
+		`curl 'https://example.com/`,
+		`curl "https://example.com/`,
+		`curl $'https://example.com/`,
+	} {
+		if _, err := cs.Commands(content); err == nil {
+			t.Errorf("Commands(%q) expected an error", content)
+		}
+	}
+}
+
+func TestCurlParse(t *testing.T) {
+	cs, err := GetCurlService()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "avatar.png"), []byte("PNG"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "body.txt"), []byte("a=1\nb=2\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		content string
+		want    curlCommand
+	}{
+		{
+			name:    "grouped short flags with the method",
+			content: `curl -sSLX POST https://example.com/ -d a=1`,
+			want:    curlCommand{Method: "POST", Url: "https://example.com/", Data: []string{"a=1"}},
+		},
+		{
+			name:    "method glued to -X",
+			content: `curl -XPUT -H 'X-A: 1' --url https://example.com/x`,
+			want:    curlCommand{Method: "PUT", Url: "https://example.com/x", Headers: []string{"X-A: 1"}},
+		},
+		{
+			name:    "burp flags and cookies",
+			content: `curl -i -s -k -X $'POST' -b $'session=xyz' --data-binary $'{\"q\":1}' $'https://example.com/search'`,
+			want:    curlCommand{Method: "POST", Url: "https://example.com/search", Cookies: []string{"session=xyz"}, Data: []string{`{"q":1}`}},
+		},
+		{
+			name:    "form upload with type relative to the curl file",
+			content: `curl https://example.com/upload -F 'avatar=@avatar.png;type=image/png' -F 'note=<body.txt' -F 'name=x;y'`,
+			want: curlCommand{Url: "https://example.com/upload", Parts: []formPart{
+				{Name: "avatar", FileName: "avatar.png", ContentType: "image/png", Content: []byte("PNG")},
+				{Name: "note", Content: []byte("a=1\nb=2\n")},
+				{Name: "name", Content: []byte("x")},
+			}},
+		},
+		{
+			name:    "data file relative to the curl file",
+			content: `curl https://example.com/ -d @body.txt --data-binary @body.txt --data-urlencode q@body.txt`,
+			want:    curlCommand{Url: "https://example.com/", Data: []string{"a=1b=2", "a=1\nb=2\n", "q=a%3D1%0Ab%3D2%0A"}},
+		},
+		{
+			name:    "get, head, compressed and auth",
+			content: `curl -G -I --compressed -u admin:secret --oauth2-bearer tok -A agent -e https://example.com/ https://example.com/q -d a=1`,
+			want: curlCommand{Url: "https://example.com/q", Data: []string{"a=1"}, Get: true, Head: true, Compressed: true, User: "admin:secret", Bearer: "tok",
+				Headers: []string{"User-Agent: agent", "Referer: https://example.com/"}},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			commands, err := cs.Commands(test.content)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := cs.Parse(commands[0], dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			test.want.Dir = dir
+			if !reflect.DeepEqual(*got, test.want) {
+				t.Errorf("Parse() =\n%+v\nwant\n%+v", *got, test.want)
+			}
+		})
+	}
+}
+
+func TestCurlParseErrors(t *testing.T) {
+	cs, err := GetCurlService()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, args := range [][]string{
+		{"-H", "X-A: 1"},
+		{"https://example.com/", "-H"},
+		{"https://example.com/", "-b", "cookies.txt"},
+		{"https://example.com/", "-d", "@missing.txt"},
+	} {
+		if _, err := cs.Parse(args, t.TempDir()); err == nil {
+			t.Errorf("Parse(%s) expected an error", strings.Join(args, " "))
+		}
+	}
+}
@@ -1,13 +1,29 @@
 package services
 
 import (
+	"bufio"
+	"bytes"
 	"encoding/json"
+	"fmt"
+	"io"
 	"os"
+	"path/filepath"
+	"sort"
+	"strings"
 	"xss/utils"
 )
 
+// StdinPath is the path that reads the standard input instead of a file
+const StdinPath = "-"
+
+// maxLineSize is the longest line ReadLines accepts
+const maxLineSize = 1024 * 1024
+
 type FileService struct {
 	jsonService *JsonService
+	stdin       *bufio.Reader
+	stdinData   []byte
+	stdinRead   bool
 }
 
 var fileServiceInstance *FileService = nil
@@ -25,8 +41,58 @@ func GetFileService() (*FileService, error) {
 	return fileServiceInstance, nil
 }
 
-// Read file as []byte
+// ExpandPath resolves a file, every file of a directory or a glob into a sorted list of files
+func (fs *FileService) ExpandPath(path string) ([]string, error) {
+	if path == StdinPath {
+		return []string{path}, nil
+	}
+
+	if strings.ContainsAny(path, "*?[") {
+		matches, err := filepath.Glob(path)
+		if err != nil {
+			return nil, err
+		}
+		if len(matches) == 0 {
+			return nil, fmt.Errorf("no files match %s", path)
+		}
+		sort.Strings(matches)
+		return matches, nil
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return []string{path}, nil
+	}
+
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var files []string
+	for _, entry := range entries {
+		// hidden files are editor swap files and the like
+		if entry.IsDir() || strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+		files = append(files, filepath.Join(path, entry.Name()))
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no files in %s", path)
+	}
+
+	return files, nil
+}
+
+// Read file as []byte, `-` reads the standard input
 func (fs *FileService) ReadFileAsBytes(path string) ([]byte, error) {
+	if path == StdinPath {
+		return fs.ReadStdin()
+	}
+
 	data, err := os.ReadFile(path)
 	if err != nil {
 		return nil, err
@@ -34,6 +100,83 @@ func (fs *FileService) ReadFileAsBytes(path string) ([]byte, error) {
 	return data, nil
 }
 
+// ReadStdin reads the standard input to its end, it is kept since the input can only be read once
+func (fs *FileService) ReadStdin() ([]byte, error) {
+	if !fs.stdinRead {
+		data, err := io.ReadAll(fs.stdinReader())
+		if err != nil {
+			return nil, err
+		}
+		fs.stdinData = data
+		fs.stdinRead = true
+	}
+	return fs.stdinData, nil
+}
+
+// stdinReader buffers the standard input so its head can be looked at before it is read
+func (fs *FileService) stdinReader() *bufio.Reader {
+	if fs.stdin == nil {
+		fs.stdin = bufio.NewReaderSize(os.Stdin, 64*1024)
+	}
+	return fs.stdin
+}
+
+// ReadHead reads up to size bytes from the start of a file without consuming the standard input
+func (fs *FileService) ReadHead(path string, size int) ([]byte, error) {
+	if path == StdinPath {
+		if fs.stdinRead {
+			return fs.stdinData[:min(size, len(fs.stdinData))], nil
+		}
+		head, err := fs.stdinReader().Peek(min(size, fs.stdinReader().Size()))
+		if err != nil && err != io.EOF && err != bufio.ErrBufferFull {
+			return nil, err
+		}
+		return head, nil
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	head := make([]byte, size)
+	read, err := io.ReadFull(file, head)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return nil, err
+	}
+	return head[:read], nil
+}
+
+// ReadLines calls each with every line of a file in turn, without holding the file in memory. The standard
+// input is the exception, it is kept so it can be read again.
+func (fs *FileService) ReadLines(path string, each func(line string) error) error {
+	var reader io.Reader
+	if path == StdinPath {
+		data, err := fs.ReadStdin()
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	} else {
+		file, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		reader = file
+	}
+
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 64*1024), maxLineSize)
+	for scanner.Scan() {
+		if err := each(scanner.Text()); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
+
 // Read file as string
 func (fs *FileService) ReadFileAsString(path string) (string, error) {
 	data, err := fs.ReadFileAsBytes(path)
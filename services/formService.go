@@ -0,0 +1,394 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"xss/utils"
+
+	"github.com/playwright-community/playwright-go"
+)
+
+// discoverFormsScript lists every form on the page with its fields, hidden ones included
+const discoverFormsScript = `() => Array.from(document.forms).map((form, index) => ({
+	index,
+	action: form.action || location.href,
+	method: (form.getAttribute('method') || 'GET').toUpperCase(),
+	fields: Array.from(form.elements)
+		.filter((el) => el.name)
+		.map((el) => ({
+			name: el.name,
+			type: (el.type || el.tagName).toLowerCase(),
+			value: el.value || '',
+		})),
+}))`
+
+// prepareFormScript fills the remaining empty fields with benign values so validation does not block the submit
+const prepareFormScript = `({ index, field }) => {
+	const form = document.forms[index];
+	if (!form) return false;
+	const benign = { email: 'test@example.com', number: '1', tel: '123456789', url: 'https://example.com', date: '2020-01-01' };
+	for (const el of form.elements) {
+		if (!el.name || el.name === field || el.value) continue;
+		if (['text', 'search', 'textarea', 'password'].includes(el.type)) el.value = 'test';
+		else if (benign[el.type]) el.value = benign[el.type];
+	}
+	return true;
+}`
+
+// setFieldScript is the fallback for fields Playwright cannot fill, hidden inputs and selects mostly
+const setFieldScript = `({ index, field, value }) => {
+	const el = document.forms[index] && document.forms[index].elements.namedItem(field);
+	if (!el) return false;
+	const target = el instanceof RadioNodeList ? el[0] : el;
+	if (target instanceof HTMLSelectElement) target.add(new Option(value, value, true, true));
+	target.value = value;
+	target.dispatchEvent(new Event('input', { bubbles: true }));
+	target.dispatchEvent(new Event('change', { bubbles: true }));
+	return true;
+}`
+
+// submitFormScript submits through requestSubmit so the page's own submit handlers run
+const submitFormScript = `({ index }) => {
+	const form = document.forms[index];
+	if (!form) return false;
+	if (typeof form.requestSubmit === 'function') form.requestSubmit();
+	else form.submit();
+	return true;
+}`
+
+// skippedFieldTypes are never filled with payloads
+var skippedFieldTypes = map[string]bool{
+	"submit": true,
+	"button": true,
+	"reset":  true,
+	"image":  true,
+	"file":   true,
+}
+
+type FormField struct {
+	Name  string `json:"name"`
+	Type  string `json:"type"`
+	Value string `json:"value"`
+}
+
+type Form struct {
+	Page   string      `json:"page"`
+	Index  int         `json:"index"`
+	Action string      `json:"action"`
+	Method string      `json:"method"`
+	Fields []FormField `json:"fields"`
+}
+
+type FormService struct {
+	Forms []Form
+
+	argService     *ArgsService
+	jsonService    *JsonService
+	monitorService *MonitorService
+	reportService  *ReportService
+	storedService  *StoredService
+
+	// done is cancelled by Ctrl+C, the handler is registered once for every scan
+	done      context.Context
+	cancel    context.CancelFunc
+	interrupt sync.Once
+}
+
+var formServiceInstance *FormService = nil
+
+// Singleton instance of FormService
+func GetFormService() (*FormService, error) {
+	if formServiceInstance == nil {
+		var argService *ArgsService
+		var jsonService *JsonService
+		var monitorService *MonitorService
+		var reportService *ReportService
+		var storedService *StoredService
+		var err error
+
+		if argService, err = GetArgsService(); err != nil {
+			return nil, err
+		}
+
+		if jsonService, err = GetJsonService(); err != nil {
+			return nil, err
+		}
+
+		if monitorService, err = GetMonitorService(); err != nil {
+			return nil, err
+		}
+
+		if reportService, err = GetReportService(); err != nil {
+			return nil, err
+		}
+
+		if storedService, err = GetStoredService(); err != nil {
+			return nil, err
+		}
+
+		formServiceInstance = &FormService{
+			argService:     argService,
+			jsonService:    jsonService,
+			monitorService: monitorService,
+			reportService:  reportService,
+			storedService:  storedService,
+		}
+	}
+
+	return formServiceInstance, nil
+}
+
+// Run discovers the forms on every page and keeps one copy of each distinct form
+func (frs *FormService) Run(ctx playwright.BrowserContext, urls UrlStream) {
+	utils.Log.Info("Running FormService")
+
+	seen := make(map[string]struct{})
+	var forms []Form
+
+	err := urls(func(pageUrl string) error {
+		pageUrl = StripInjectionPoints(pageUrl)
+
+		discovered, err := frs.Discover(ctx, pageUrl)
+		if err != nil {
+			utils.Log.Error(fmt.Sprintf("Error discovering forms on %s: %s", pageUrl, err))
+			return nil
+		}
+
+		for _, form := range discovered {
+			key := frs.formKey(form)
+			if _, exists := seen[key]; exists {
+				continue
+			}
+			seen[key] = struct{}{}
+			forms = append(forms, form)
+
+			var fields []string
+			for _, field := range form.Fields {
+				fields = append(fields, fmt.Sprintf("%s(%s)", field.Name, field.Type))
+			}
+			utils.Log.Info(fmt.Sprintf("Form found: %s %s on %s fields: %s", form.Method, form.Action, form.Page, strings.Join(fields, ", ")))
+		}
+		return nil
+	})
+	if err != nil {
+		utils.Log.Error(fmt.Sprintf("Error reading the URLs: %s", err))
+	}
+
+	utils.Log.Info(fmt.Sprintf("Forms discovered: [%d]", len(forms)))
+	frs.Forms = forms
+}
+
+// Discover loads the page and lists its forms
+func (frs *FormService) Discover(ctx playwright.BrowserContext, pageUrl string) ([]Form, error) {
+	options := frs.argService.GetAll()
+
+	page, err := ctx.NewPage()
+	if err != nil {
+		return nil, err
+	}
+	defer page.Close()
+
+	if _, err := page.Goto(pageUrl, playwright.PageGotoOptions{
+		Timeout: playwright.Float(float64(options[ArgKeys.Timeout].(int))),
+	}); err != nil {
+		return nil, err
+	}
+
+	result, err := page.Evaluate(discoverFormsScript)
+	if err != nil {
+		return nil, err
+	}
+
+	var forms []Form
+	if resultJson, err := frs.jsonService.StructToJson(result); err != nil {
+		return nil, err
+	} else if err := frs.jsonService.JsonToStruct(resultJson, &forms); err != nil {
+		return nil, err
+	}
+
+	for ix := range forms {
+		forms[ix].Page = pageUrl
+	}
+
+	return forms, nil
+}
+
+// formKey identifies a form by where it submits and which fields it has, so shared forms are scanned once
+func (frs *FormService) formKey(form Form) string {
+	var names []string
+	for _, field := range form.Fields {
+		names = append(names, field.Name)
+	}
+	sort.Strings(names)
+
+	return form.Method + " " + form.Action + " " + strings.Join(names, ",")
+}
+
+// Scan fills every field of every discovered form with each payload and submits it in the page
+func (frs *FormService) Scan(ctx playwright.BrowserContext, payloads []string, marker string) error {
+	options := frs.argService.GetAll()
+
+	type formJob struct {
+		form    Form
+		field   FormField
+		payload string
+	}
+
+	var jobs []formJob
+	for _, form := range frs.Forms {
+		for _, field := range form.Fields {
+			if skippedFieldTypes[field.Type] {
+				continue
+			}
+			for _, payload := range payloads {
+				jobs = append(jobs, formJob{form: form, field: field, payload: payload})
+			}
+		}
+	}
+
+	if len(jobs) == 0 {
+		utils.Log.Info("No form fields to scan")
+		return nil
+	}
+
+	utils.Log.Info(fmt.Sprintf("Generated [%d] form submissions", len(jobs)))
+
+	frs.interrupt.Do(func() {
+		frs.done, frs.cancel = context.WithCancel(context.Background())
+		utils.HandleCtrlC(frs.cancel)
+	})
+	doneCtx := frs.done
+
+	var wg sync.WaitGroup
+	threads := max(1, options[ArgKeys.Threads].(int))
+	slots := make(chan struct{}, threads)
+	found := 0
+	var m sync.Mutex
+
+outer:
+	for ix, job := range jobs {
+		select {
+		case <-doneCtx.Done():
+			utils.Log.Warn("Ending the form scan, last submission index: ", ix)
+			break outer
+		case slots <- struct{}{}:
+		}
+
+		if ix%threads == 0 {
+			utils.Log.Info(fmt.Sprintf("Scanning form progress: %d/%d %d%%", ix+1, len(jobs), (ix+1)*100/len(jobs)))
+		}
+
+		wg.Add(1)
+		go func(job formJob) {
+			defer wg.Done()
+			defer func() { <-slots }()
+
+			expected := marker
+			payload := job.payload
+			if frs.storedService.Enabled() {
+				var err error
+				if expected, err = frs.storedService.Inject(job.form.Action, "form", job.field.Name); err != nil {
+					utils.Log.Error(fmt.Sprintf("Error submitting form %s: %s", job.form.Action, err))
+					return
+				}
+				payload = strings.ReplaceAll(payload, marker, expected)
+				defer frs.storedService.Verify()
+			}
+
+			hits, err := frs.Send(ctx, job.form, job.field, payload, expected)
+			if err != nil {
+				utils.Log.Error(fmt.Sprintf("Error submitting form %s: %s", job.form.Action, err))
+			}
+
+			// one finding per submission, the first hit is the one that tells where it fired
+			if len(hits) > 0 {
+				hit := hits[0]
+				if !hit.Sink && hit.Value != expected {
+					utils.Log.Warn(fmt.Sprintf("Alert found with UUID missmatch: %s %s field %s", hit.Value, job.form.Action, job.field.Name))
+				} else {
+					utils.Log.Success(fmt.Sprintf("XSS found: %s field %s (%s)", job.form.Action, job.field.Name, hit.Kind))
+				}
+
+				frs.reportService.AddFinding(Finding{
+					Target:   job.form.Action,
+					Location: "form",
+					Param:    job.field.Name,
+					Trigger:  hit.Kind,
+				})
+				m.Lock()
+				found++
+				m.Unlock()
+			}
+
+			if options[ArgKeys.Delay].(int) > 0 {
+				time.Sleep(time.Duration(options[ArgKeys.Delay].(int)) * time.Millisecond)
+			}
+		}(job)
+	}
+
+	wg.Wait()
+
+	if found > 0 {
+		utils.Log.Success(fmt.Sprintf("Form XSS found: %d", found))
+	} else {
+		utils.Log.Info("No form XSS found")
+	}
+
+	if options[ArgKeys.Report].(string) != "" {
+		if err := frs.reportService.SaveReport(options[ArgKeys.Report].(string)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Send loads the form page, fills the field with the payload and submits the form like a user would
+func (frs *FormService) Send(ctx playwright.BrowserContext, form Form, field FormField, payload string, marker string) ([]MonitorHit, error) {
+	options := frs.argService.GetAll()
+	timeout := float64(options[ArgKeys.Timeout].(int))
+
+	page, err := ctx.NewPage()
+	if err != nil {
+		return nil, err
+	}
+	defer page.Close()
+
+	monitor, err := frs.monitorService.Attach(page, marker)
+	if err != nil {
+		return nil, err
+	}
+	defer frs.monitorService.Detach(monitor)
+
+	if _, err := page.Goto(form.Page, playwright.PageGotoOptions{Timeout: playwright.Float(timeout)}); err != nil {
+		return nil, err
+	}
+
+	// whatever fired on the plain page load does not belong to this field
+	frs.monitorService.Reset(monitor)
+
+	args := map[string]interface{}{"index": form.Index, "field": field.Name, "value": payload}
+	if _, err := page.Evaluate(prepareFormScript, args); err != nil {
+		return nil, err
+	}
+
+	input := page.Locator("form").Nth(form.Index).Locator(fmt.Sprintf("[name=%q]", field.Name)).First()
+	if err := input.Fill(payload, playwright.LocatorFillOptions{Timeout: playwright.Float(1000)}); err != nil {
+		if _, err := page.Evaluate(setFieldScript, args); err != nil {
+			return nil, err
+		}
+	}
+
+	// the submit usually navigates away, which can tear down the evaluation context mid call
+	page.Evaluate(submitFormScript, args)
+	page.WaitForLoadState(playwright.PageWaitForLoadStateOptions{
+		State:   playwright.LoadStateLoad,
+		Timeout: playwright.Float(timeout),
+	})
+
+	return frs.monitorService.Wait(monitor, time.Duration(1000)*time.Millisecond), nil
+}
@@ -3,7 +3,6 @@ package services
 import (
 	"context"
 	"fmt"
-	"net/url"
 	"strconv"
 	"strings"
 	"sync"
@@ -13,16 +12,41 @@ import (
 	playwright "github.com/playwright-community/playwright-go"
 )
 
+// target is a generated injection with its index in the scan and the index of its URL in the input
+type target struct {
+	Injection Injection
+	Index     int
+	UrlIndex  int
+}
+
+// UrlStream calls each with every URL in turn and stops at the first error
+type UrlStream func(each func(url string) error) error
+
 type GetService struct {
-	CombinedUrls []string
-	Urls         []string
-	Payloads     []string
-	UUID         string
-
-	fileService *FileService
-	argService  *ArgsService
-	jsonService *JsonService
-	urlService  *UrlService
+	UrlCount    int
+	Origins     []string
+	Seeds       []string
+	Payloads    []string
+	RawPayloads []string
+	Discovered  map[string][]string
+	Points      map[string][]string
+	UrlHeaders  map[string]map[string]string
+	UUID        string
+
+	fileService     *FileService
+	argService      *ArgsService
+	jsonService     *JsonService
+	urlService      *UrlService
+	reportService   *ReportService
+	storedService   *StoredService
+	paramService    *ParamService
+	nestedService   *NestedService
+	strategy        *StrategyService
+	headerService   *HeaderService
+	importService   *ImportService
+	scopeService    *ScopeService
+	resourceService *ResourceService
+	identityService *IdentityService
 }
 
 var getServiceInstance *GetService = nil
@@ -34,6 +58,16 @@ func GetGetService() (*GetService, error) {
 		var argService *ArgsService
 		var jsonService *JsonService
 		var urlService *UrlService
+		var reportService *ReportService
+		var storedService *StoredService
+		var paramService *ParamService
+		var nestedService *NestedService
+		var strategy *StrategyService
+		var headerService *HeaderService
+		var importService *ImportService
+		var scopeService *ScopeService
+		var resourceService *ResourceService
+		var identityService *IdentityService
 		var err error
 
 		if fileService, err = GetFileService(); err != nil {
@@ -52,11 +86,61 @@ func GetGetService() (*GetService, error) {
 			return nil, err
 		}
 
+		if reportService, err = GetReportService(); err != nil {
+			return nil, err
+		}
+
+		if storedService, err = GetStoredService(); err != nil {
+			return nil, err
+		}
+
+		if paramService, err = GetParamService(); err != nil {
+			return nil, err
+		}
+
+		if nestedService, err = GetNestedService(); err != nil {
+			return nil, err
+		}
+
+		if strategy, err = GetStrategyService(); err != nil {
+			return nil, err
+		}
+
+		if headerService, err = GetHeaderService(); err != nil {
+			return nil, err
+		}
+
+		if importService, err = GetImportService(); err != nil {
+			return nil, err
+		}
+
+		if scopeService, err = GetScopeService(); err != nil {
+			return nil, err
+		}
+
+		if resourceService, err = GetResourceService(); err != nil {
+			return nil, err
+		}
+
+		if identityService, err = GetIdentityService(); err != nil {
+			return nil, err
+		}
+
 		getServiceInstance = &GetService{
-			fileService: fileService,
-			argService:  argService,
-			jsonService: jsonService,
-			urlService:  urlService,
+			fileService:     fileService,
+			argService:      argService,
+			jsonService:     jsonService,
+			urlService:      urlService,
+			reportService:   reportService,
+			storedService:   storedService,
+			paramService:    paramService,
+			nestedService:   nestedService,
+			strategy:        strategy,
+			headerService:   headerService,
+			importService:   importService,
+			scopeService:    scopeService,
+			resourceService: resourceService,
+			identityService: identityService,
 		}
 	}
 
@@ -75,7 +159,7 @@ func (gs *GetService) Run() {
 	if err := gs.GetUrls(); err != nil {
 		utils.HandleErr(err)
 	} else {
-		utils.Log.Info(fmt.Sprintf("URLs parsed: [%d]", len(gs.Urls)))
+		utils.Log.Info(fmt.Sprintf("URLs parsed: [%d]", gs.UrlCount))
 	}
 
 	if err := gs.GetPayloads(); err != nil {
@@ -84,43 +168,339 @@ func (gs *GetService) Run() {
 		utils.Log.Info(fmt.Sprintf("Payloads parsed: [%d]", len(gs.Payloads)))
 	}
 
-	var combinedUrls []string
-	for _, url := range gs.Urls {
-		newUrls := gs.urlService.CombineUrlQueryWithPayload(url, gs.Payloads)
-		combinedUrls = append(combinedUrls, newUrls...)
+	if err := gs.headerService.Run(); err != nil {
+		utils.HandleErr(err)
+	}
+
+	if points, err := gs.strategy.GetPointPayloads(gs.UUID); err != nil {
+		utils.HandleErr(err)
+	} else {
+		gs.Points = make(map[string][]string)
+		for point, payloads := range points {
+			for _, payload := range payloads {
+				gs.Points[point] = append(gs.Points[point], gs.urlService.EscapePayload(payload))
+			}
+		}
 	}
 
-	utils.Log.Info(fmt.Sprintf("Generated [%d] URLs", len(combinedUrls)))
-	gs.CombinedUrls = utils.RemoveDuplicates(combinedUrls)
+	if discover, err := gs.argService.Get(ArgKeys.Discover); err == nil && discover.(bool) {
+		if gs.Discovered, err = gs.paramService.Discover(gs.EachUrl, gs.UUID, gs.RequestHeaders); err != nil {
+			utils.HandleErr(err)
+		}
+	}
 
 	time.Sleep(2 * time.Second)
 }
 
-func (gs *GetService) GetUrls() error {
-	var urls []string
+// RequestHeaders returns the headers a plain request for the URL is sent with, the same ones the scan sends:
+// the static -H headers, the URL's own headers and the cookies of the injecting identity
+func (gs *GetService) RequestHeaders(url string) map[string]string {
+	headers := utils.CloneMap(gs.headerService.Headers)
+	for name, value := range gs.UrlHeaders[url] {
+		if key, _ := HeaderValue(headers, name); key != "" {
+			delete(headers, key)
+		}
+		headers[name] = value
+	}
 
-	if urlsFile, err := gs.argService.Get(ArgKeys.Urls); err != nil {
-		return err
-	} else {
-		if fileContent, err := gs.fileService.ReadFileAsString(urlsFile.(string)); err != nil {
-			return err
+	var cookies []string
+	for name, value := range gs.headerService.Cookies {
+		cookies = append(cookies, name+"="+value)
+	}
+	if len(gs.identityService.Identities) > 0 {
+		if jar, err := gs.identityService.Injector().Ctx.Cookies(StripInjectionPoints(url)); err == nil {
+			for _, cookie := range jar {
+				cookies = append(cookies, cookie.Name+"="+cookie.Value)
+			}
+		}
+	}
+
+	if len(cookies) > 0 {
+		if key, value := HeaderValue(headers, "Cookie"); key != "" {
+			headers[key] = value + "; " + strings.Join(cookies, "; ")
 		} else {
-			urls = strings.Split(fileContent, "\n")
+			headers["Cookie"] = strings.Join(cookies, "; ")
+		}
+	}
+
+	return headers
+}
+
+// templatePayload stands in for the payloads when generating the injection templates of a URL, no escaping
+// changes it
+const templatePayload = "xsstemplatepayload"
+
+// Targets generates the injections of every URL lazily and in a stable order, skipping duplicates and the
+// first skip ones an earlier run already scanned. The URLs are read again as the injections are generated,
+// only the injections of one URL and the channel buffer are held in memory. Duplicates are tracked by the
+// hash of their template, the injection with the payload left out, so the set grows with the injection
+// positions and not with the payloads. The channel is closed at the end or when ctx is done.
+func (gs *GetService) Targets(ctx context.Context, skip int) <-chan target {
+	targets := make(chan target, max(1, gs.argService.GetAll()[ArgKeys.Threads].(int)))
+
+	go func() {
+		defer close(targets)
+
+		seen := utils.NewHashSet()
+		index, duplicates, urlIndex := 0, 0, 0
+		err := gs.EachUrl(func(url string) error {
+			defer func() { urlIndex++ }()
+
+			templates := gs.injectionTemplates(url)
+			fresh := make(map[string]bool)
+			sent := make(map[string]struct{})
+
+			for _, injection := range gs.CombineUrl(url) {
+				key := InjectionKey(injection)
+
+				// every payload of a template another URL already had is a duplicate of that URL's
+				template, ok := templates[injectionPosition(injection)]
+				if !ok {
+					template = key
+				}
+				isNew, decided := fresh[template]
+				if !decided {
+					isNew = seen.Add(template)
+					fresh[template] = isNew
+				}
+
+				if _, exists := sent[key]; exists || !isNew {
+					duplicates++
+					continue
+				}
+				sent[key] = struct{}{}
+
+				index++
+				if index <= skip {
+					continue
+				}
+
+				select {
+				case targets <- target{Injection: injection, Index: index - 1, UrlIndex: urlIndex}:
+				case <-ctx.Done():
+					return ctx.Err()
+				}
+			}
+			return nil
+		})
+
+		switch {
+		case ctx.Err() != nil:
+		case err != nil:
+			utils.Log.Error(fmt.Sprintf("Error reading the URLs: %s", err))
+		default:
+			utils.Log.Info(fmt.Sprintf("Generated [%d] URLs, duplicates skipped: [%d]", index, duplicates))
+		}
+	}()
+
+	return targets
+}
+
+// injectionTemplates returns the key of the injection template at every injection position of the URL
+func (gs *GetService) injectionTemplates(url string) map[string]string {
+	points := make(map[string][]string)
+	for point := range gs.Points {
+		points[point] = []string{templatePayload}
+	}
+
+	templates := make(map[string]string)
+	for _, injection := range gs.combine(url, []string{templatePayload}, []string{templatePayload}, points) {
+		templates[injectionPosition(injection)] = InjectionKey(injection)
+	}
+	return templates
+}
+
+// injectionPosition identifies where in the request an injection puts its payload
+func injectionPosition(injection Injection) string {
+	return injection.Location + "\x00" + injection.Param + "\x00" + injection.Variant
+}
+
+// CombineUrl generates every injection for a single URL, sent with the URL's own headers when it has any
+func (gs *GetService) CombineUrl(url string) []Injection {
+	return gs.combine(url, gs.Payloads, gs.RawPayloads, gs.Points)
+}
+
+func (gs *GetService) combine(url string, payloads []string, rawPayloads []string, points map[string][]string) []Injection {
+	injections := gs.combineUrl(url, payloads, rawPayloads, points)
+
+	if headers := gs.UrlHeaders[url]; len(headers) > 0 {
+		for ix := range injections {
+			merged := utils.CloneMap(headers)
+			for name, value := range injections[ix].Headers {
+				if key, _ := HeaderValue(merged, name); key != "" {
+					delete(merged, key)
+				}
+				merged[name] = value
+			}
+			injections[ix].Headers = merged
+		}
+	}
+
+	return injections
+}
+
+// combineUrl generates every injection for a single URL with the enabled injection modes
+func (gs *GetService) combineUrl(url string, payloads []string, rawPayloads []string, pointPayloads map[string][]string) []Injection {
+	options := gs.argService.GetAll()
+
+	// header and cookie injections reuse the URL as it is, only the request around it changes
+	combinedUrls := gs.headerService.CombineHeadersWithPayload(url, rawPayloads)
+
+	if points := gs.strategy.Points(url); len(points) > 0 {
+		for _, attack := range gs.strategy.Combine(points, payloads, pointPayloads) {
+			combinedUrls = append(combinedUrls, Injection{
+				Url:      gs.strategy.Fill(url, attack),
+				Location: "marker",
+				Param:    strings.Join(attack.Targets, ","),
+				Variant:  options[ArgKeys.Strategy].(string),
+			})
+		}
+		return combinedUrls
+	}
+
+	combinedUrls = append(combinedUrls, gs.urlService.CombineUrlQueryWithPayload(url, payloads)...)
+
+	if options[ArgKeys.Path].(bool) {
+		combinedUrls = append(combinedUrls, gs.urlService.CombineUrlPathWithPayload(url, rawPayloads)...)
+	}
+
+	if options[ArgKeys.Names].(bool) {
+		combinedUrls = append(combinedUrls, gs.urlService.CombineUrlParamNameWithPayload(url, payloads)...)
+	}
+
+	if options[ArgKeys.Hpp].(bool) {
+		combinedUrls = append(combinedUrls, gs.urlService.CombineUrlPollutionWithPayload(url, rawPayloads)...)
+	}
+
+	if options[ArgKeys.Nested].(bool) {
+		combinedUrls = append(combinedUrls, gs.nestedService.CombineUrlNestedWithPayload(url, rawPayloads)...)
+	}
+
+	for _, key := range gs.Discovered[url] {
+		combinedUrls = append(combinedUrls, gs.urlService.CombineUrlParamWithPayload(url, key, payloads)...)
+	}
+
+	return combinedUrls
+}
+
+// GetUrls reads the URLs once to count them and collect their origins and the crawl seeds, the URLs
+// themselves are not kept, EachUrl reads them again
+func (gs *GetService) GetUrls() error {
+	pages, _ := gs.argService.GetAll()[ArgKeys.Pages].(int)
+	origins := make(map[string]struct{})
+	gs.UrlCount, gs.Origins, gs.Seeds = 0, nil, nil
+
+	return gs.readUrls(true, func(url string) error {
+		gs.UrlCount++
+
+		origin := gs.urlService.Origin(url)
+		if origin == "" {
+			return nil
+		}
+		_, known := origins[origin]
+
+		// the crawl visits no more pages than the limit, later URLs only matter for the hosts they add
+		if !known || len(gs.Seeds) < pages {
+			gs.Seeds = append(gs.Seeds, url)
+		}
+		if !known {
+			origins[origin] = struct{}{}
+			gs.Origins = append(gs.Origins, origin)
+		}
+		return nil
+	})
+}
+
+// EachUrl calls each with every URL that GetUrls counted, in the same order, reading the sources again
+func (gs *GetService) EachUrl(each func(url string) error) error {
+	return gs.readUrls(false, each)
+}
+
+// readUrls reads the URLs of every source, dropping the out of scope, repeated and collapsed ones as they
+// are read so the files are never held in memory, and logs what was dropped when stats is set
+func (gs *GetService) readUrls(stats bool, each func(url string) error) (err error) {
+	exact, normalized := utils.NewHashSet(), utils.NewHashSet()
+	shapes := make(map[string]int)
+	shapeLimit, _ := gs.argService.GetAll()[ArgKeys.Shape].(int)
+	read, outOfScope, duplicates, equivalent, collapsed, kept := 0, 0, 0, 0, 0, 0
+
+	keep := func(url string) bool {
+		read++
+		switch {
+		case !gs.scopeService.InScope(url):
+			outOfScope++
+			return false
+		case !exact.Add(url):
+			duplicates++
+			return false
+		case !normalized.Add(gs.urlService.Normalize(url)):
+			equivalent++
+			return false
+		}
+
+		// a URL with payload markers is a target of its own, never an example of its shape
+		if shapeLimit > 0 && !strings.Contains(url, "{") {
+			shape := gs.urlService.Shape(url)
+			if shapes[shape] >= shapeLimit {
+				collapsed++
+				return false
+			}
+			shapes[shape]++
 		}
+		kept++
+		return true
 	}
 
-	var validUrls []string
-	for _, url := range urls {
-		if strings.Trim(url, " ") == "" {
+	defer func() {
+		if err != nil || !stats {
+			return
+		}
+		utils.Log.Info(fmt.Sprintf("URLs read: [%d], out of scope: [%d], exact duplicates: [%d], equal after normalization: [%d], collapsed by endpoint shape: [%d], kept: [%d]",
+			read, outOfScope, duplicates, equivalent, collapsed, kept))
+		if shapeLimit > 0 {
+			utils.Log.Info(fmt.Sprintf("Endpoint shapes: [%d], up to [%d] URLs kept of each", len(shapes), shapeLimit))
+		}
+	}()
+
+	// imported GET requests bring their own headers and cookies along
+	if gs.importService.Imported() {
+		gs.UrlHeaders = gs.importService.Headers
+		for _, url := range gs.importService.Urls {
+			if keep(url) {
+				if err := each(url); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+
+	add := func(url string) error {
+		url = strings.TrimSpace(url)
+		if url != "" && gs.urlService.ValidateUrl(url) && keep(url) {
+			return each(url)
+		}
+		return nil
+	}
+
+	// every -u value is a URL, a file of URLs or - for the standard input
+	sources, err := gs.argService.Get(ArgKeys.Urls)
+	if err != nil {
+		return err
+	}
+	for _, source := range sources.([]string) {
+		if gs.urlService.IsLiteralUrl(source) {
+			if err := add(source); err != nil {
+				return err
+			}
 			continue
 		}
 
-		if gs.urlService.ValidateUrl(url) {
-			validUrls = append(validUrls, url)
+		if err := gs.fileService.ReadLines(source, add); err != nil {
+			return err
 		}
 	}
 
-	gs.Urls = validUrls
 	return nil
 }
 
@@ -138,20 +518,19 @@ func (gs *GetService) GetPayloads() error {
 	}
 
 	var validPayloads []string
+	var rawPayloads []string
 	for _, payload := range payloads {
 		if strings.Trim(payload, " ") == "" {
 			continue
 		}
 
 		formattedPayload := strings.Replace(payload, "###", gs.UUID, -1)
-		if _, err := url.ParseQuery(fmt.Sprintf("?id=%s", formattedPayload)); err != nil {
-			validPayloads = append(validPayloads, url.QueryEscape(formattedPayload))
-		} else {
-			validPayloads = append(validPayloads, formattedPayload)
-		}
+		rawPayloads = append(rawPayloads, formattedPayload)
+		validPayloads = append(validPayloads, gs.urlService.EscapePayload(formattedPayload))
 	}
 
 	gs.Payloads = validPayloads
+	gs.RawPayloads = rawPayloads
 	return nil
 }
 
@@ -162,15 +541,58 @@ func (gs *GetService) GetUUID() error {
 	return nil
 }
 
-func (gs *GetService) Send(ctx playwright.BrowserContext, url string, pageIndex int) (foundXss bool, _ error) {
+// Send loads the injection in a new page and waits for its dialog. offScope is the page the dialog fired on when
+// the target redirected there and the scope rules leave it out, the finding may not belong to the target.
+func (gs *GetService) Send(ctx playwright.BrowserContext, injection Injection, pageIndex int) (foundXss bool, offScope string, _ error) {
 	options := gs.argService.GetAll()
+	url := injection.Url
+
+	marker := gs.UUID
+	if gs.storedService.Enabled() {
+		// every injection gets its own canary so an execution on a verification page can be traced back to it
+		var err error
+		if marker, err = gs.storedService.Inject(url, injection.Location, injection.Param); err != nil {
+			return false, "", err
+		}
+		url = strings.ReplaceAll(url, gs.UUID, marker)
+		injection.Headers = utils.ReplaceInMap(injection.Headers, gs.UUID, marker)
+		injection.Cookies = utils.ReplaceInMap(injection.Cookies, gs.UUID, marker)
+		defer gs.storedService.Verify()
+	}
 
 	var page playwright.Page
 	var err error
 
+	if len(injection.Cookies) > 0 {
+		if ctx, err = gs.headerService.CookieContext(ctx, url, injection.Cookies); err != nil {
+			return false, "", err
+		}
+		defer ctx.Close()
+	}
+
 	page, err = ctx.NewPage()
 	if err != nil {
-		return false, err
+		return false, "", err
+	}
+	gs.resourceService.Track(page)
+
+	// the page's headers replace the context's static ones for its requests, so they go along too
+	gotoOptions := playwright.PageGotoOptions{}
+	headers := utils.CloneMap(gs.headerService.Headers)
+	for name, value := range injection.Headers {
+		if strings.EqualFold(name, "Referer") {
+			gotoOptions.Referer = playwright.String(value)
+		} else {
+			if key, _ := HeaderValue(headers, name); key != "" {
+				delete(headers, key)
+			}
+			headers[name] = value
+		}
+	}
+
+	if err := gs.headerService.RouteHeaders(page, headers); err != nil {
+		page.Close()
+		return false, "", err
 	}
 
 	dialogChan := make(chan bool, 1)
@@ -182,11 +604,15 @@ func (gs *GetService) Send(ctx playwright.BrowserContext, url string, pageIndex
 		if err := dialog.Accept(); err != nil {
 			utils.Log.Error(fmt.Sprintf("Error accepting dialog %s: %s, %s", dialogType, dialogMsg, url))
 		} else {
-			if dialogMsg == gs.UUID {
+			if dialogMsg == marker {
 				utils.Log.Success(fmt.Sprintf("XSS found: %s", url))
 			} else {
 				utils.Log.Warn(fmt.Sprintf("Alert found with UUID missmatch: %s %s", dialogMsg, url))
 			}
+			if landed := page.URL(); gs.scopeService.Block(landed) != "" {
+				utils.Log.Warn(fmt.Sprintf("XSS fired after leaving the scope, on: %s", landed))
+				offScope = landed
+			}
 			foundXss = true
 			dialogChan <- true
 		}
@@ -213,26 +639,27 @@ func (gs *GetService) Send(ctx playwright.BrowserContext, url string, pageIndex
 		page.Close()
 	}()
 
-	if _, err := page.Goto(url); err != nil {
-		return false, err
+	loadStart := time.Now()
+	if _, err := page.Goto(url, gotoOptions); err != nil {
+		return false, "", err
 	}
 
 	select {
 	case <-pageLoadChan:
-		// Page loaded
+		gs.resourceService.Loaded(page, time.Since(loadStart))
 	case <-time.After(time.Duration(options[ArgKeys.Timeout].(int)) * time.Millisecond):
 		utils.Log.Warn(fmt.Sprintf("Timeout loading page: %s", url))
-		return false, nil
+		return false, "", nil
 	}
 
 	select {
 	case <-dialogChan:
-		return true, nil
+		return true, offScope, nil
 	case <-time.After(time.Duration(1000) * time.Millisecond):
 		//utils.Log.Warn(fmt.Sprintf("1000ms timeout waiting for dialog: %s", url))
 	}
 
-	return false, nil
+	return false, "", nil
 }
 
 func (gs *GetService) Scan(ctx playwright.BrowserContext) error {
@@ -240,14 +667,13 @@ func (gs *GetService) Scan(ctx playwright.BrowserContext) error {
 
 	ctx.NewPage()
 
-	continueFrom := min(max(0, options[ArgKeys.Continue].(int)), len(gs.CombinedUrls))
-	urlsToScan := gs.CombinedUrls[continueFrom:]
+	continueFrom := max(0, options[ArgKeys.Continue].(int))
 
 	var wg sync.WaitGroup
 
-	tabCount := min(options[ArgKeys.Threads].(int), len(urlsToScan))
+	tabCount := max(1, options[ArgKeys.Threads].(int))
 
-	urlsChan := make(chan string, tabCount)
+	urlsChan := make(chan Injection, tabCount)
 
 	if continueFrom > 0 {
 		utils.Log.Info(fmt.Sprintf("Continuing from index: %d", continueFrom))
@@ -255,21 +681,26 @@ func (gs *GetService) Scan(ctx playwright.BrowserContext) error {
 
 	doneCtx, cancel := context.WithCancel(context.Background())
 
+	// the injections are generated while the scan runs, progress goes by the input URLs
+	targets := gs.Targets(doneCtx, continueFrom)
+
 	go func() {
+		scanned := 0
 	outer:
-		for ix, url := range urlsToScan {
+		for target := range targets {
 			wg.Add(1)
-			urlsChan <- url
+			urlsChan <- target.Injection
 
 			select {
 			case <-doneCtx.Done():
-				utils.Log.Warn("Ending the scan, last url index: ", ix)
+				utils.Log.Warn("Ending the scan, last url index: ", target.Index)
 				break outer
 			default:
-				if ix%options[ArgKeys.Threads].(int) == 0 {
-					utils.Log.Info(fmt.Sprintf("Scanning URL progress: %d/%d %d%%", ix+1, len(urlsToScan), (ix+1)*100/len(urlsToScan)))
+				if scanned%tabCount == 0 {
+					utils.Log.Info(fmt.Sprintf("Scanning URL progress: %d, input URL %d/%d %d%%", target.Index+1, target.UrlIndex+1, gs.UrlCount, (target.UrlIndex+1)*100/max(1, gs.UrlCount)))
 				}
 			}
+			scanned++
 		}
 		close(urlsChan)
 	}()
@@ -279,7 +710,7 @@ func (gs *GetService) Scan(ctx playwright.BrowserContext) error {
 		freeSlotChan <- i
 	}
 
-	var foundXss []string
+	var foundXss []Injection
 	var m sync.Mutex
 
 	utils.HandleCtrlC(cancel)
@@ -311,12 +742,13 @@ func (gs *GetService) Scan(ctx playwright.BrowserContext) error {
 		if pageIndex == -1 || !ok || shouldBreak {
 			break
 		}
-		go func(url string, pageIndex int) {
-			if found, err := gs.Send(ctx, url, pageIndex); err != nil {
+		go func(url Injection, pageIndex int) {
+			if found, offScope, err := gs.Send(ctx, url, pageIndex); err != nil {
 				utils.Log.Error(fmt.Sprintf("Error sending request: %s", err))
 			} else if found {
 				m.Lock()
 				foundXss = append(foundXss, url)
+				gs.reportService.AddFinding(Finding{Target: url.Url, Location: url.Location, Param: url.Param, Variant: url.Variant, OffScope: offScope})
 				utils.Log.Success(fmt.Sprintf("Found total %d urls", len(foundXss)))
 				m.Unlock()
 			}
@@ -341,7 +773,7 @@ func (gs *GetService) Scan(ctx playwright.BrowserContext) error {
 		if len(foundXss) > 0 {
 			utils.Log.Success(fmt.Sprintf("XSS found: %d", len(foundXss)))
 			for _, url := range foundXss {
-				utils.Log.Success(url)
+				utils.Log.Success(url.Url)
 			}
 			utils.Log.Success(fmt.Sprintf("XSS found: %d", len(foundXss)))
 		} else {
@@ -349,14 +781,8 @@ func (gs *GetService) Scan(ctx playwright.BrowserContext) error {
 		}
 
 		if options[ArgKeys.Report].(string) != "" {
-			if foundXssJson, err := gs.jsonService.ArrayToString(foundXss); err != nil {
+			if err := gs.reportService.SaveReport(options[ArgKeys.Report].(string)); err != nil {
 				utils.HandleErr(err)
-			} else {
-				if err := gs.fileService.WriteFileAsString(options[ArgKeys.Report].(string), foundXssJson); err != nil {
-					utils.HandleErr(err)
-				} else {
-					utils.Log.Info(fmt.Sprintf("Report saved to: %s", options[ArgKeys.Report].(string)))
-				}
 			}
 		}
 	}()
@@ -0,0 +1,150 @@
+package services
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func newTestGetService(t *testing.T, urls []string) *GetService {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "urls.txt")
+	if err := os.WriteFile(path, []byte(strings.Join(urls, "\n")+"\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	argService := &ArgsService{argsMap: map[ArgKey]interface{}{
+		ArgKeys.Urls:     []string{path},
+		ArgKeys.Threads:  1,
+		ArgKeys.Pages:    2,
+		ArgKeys.Shape:    0,
+		ArgKeys.Strategy: StrategySniper,
+		ArgKeys.Combos:   0,
+		ArgKeys.Path:     false,
+		ArgKeys.Names:    false,
+		ArgKeys.Hpp:      false,
+		ArgKeys.Nested:   false,
+	}}
+	fileService, err := GetFileService()
+	if err != nil {
+		t.Fatal(err)
+	}
+	urlService, err := GetUrlService()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return &GetService{
+		Payloads:      []string{"p1", "p2"},
+		RawPayloads:   []string{"p1", "p2"},
+		argService:    argService,
+		fileService:   fileService,
+		urlService:    urlService,
+		strategy:      &StrategyService{argService: argService},
+		headerService: &HeaderService{argService: argService},
+		scopeService:  &ScopeService{},
+		importService: &ImportService{argService: argService, format: FormatUrls},
+	}
+}
+
+func TestGetUrls(t *testing.T) {
+	gs := newTestGetService(t, []string{
+		"https://example.com/a?q=1",
+		"https://example.com/a?q=1",
+		"https://example.com/b?q=1",
+		"https://example.com/c?q=1",
+		"https://other.example.com/d?q=1",
+	})
+
+	if err := gs.GetUrls(); err != nil {
+		t.Fatal(err)
+	}
+	if gs.UrlCount != 4 {
+		t.Errorf("UrlCount = %d, want 4", gs.UrlCount)
+	}
+	if want := []string{"https://example.com/", "https://other.example.com/"}; !reflect.DeepEqual(gs.Origins, want) {
+		t.Errorf("Origins = %v, want %v", gs.Origins, want)
+	}
+	// the crawl limit is 2 pages, the URL of a new host is a seed anyway
+	if want := []string{"https://example.com/a?q=1", "https://example.com/b?q=1", "https://other.example.com/d?q=1"}; !reflect.DeepEqual(gs.Seeds, want) {
+		t.Errorf("Seeds = %v, want %v", gs.Seeds, want)
+	}
+
+	// reading the URLs again gives the same ones in the same order
+	var urls []string
+	if err := gs.EachUrl(func(url string) error {
+		urls = append(urls, url)
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"https://example.com/a?q=1", "https://example.com/b?q=1", "https://example.com/c?q=1", "https://other.example.com/d?q=1"}
+	if !reflect.DeepEqual(urls, want) {
+		t.Errorf("EachUrl() = %v, want %v", urls, want)
+	}
+}
+
+func TestTargetsDedupByTemplate(t *testing.T) {
+	gs := newTestGetService(t, []string{
+		"https://example.com/a?q=1",
+		// the same injection template as the first URL, every payload of it is a duplicate
+		"https://example.com/a?q=2",
+		// q is templated with r=1 kept, so both positions are new
+		"https://example.com/a?q=1&r=1",
+		"https://example.com/b?q=1",
+	})
+
+	tests := []struct {
+		name      string
+		skip      int
+		wantUrls  []string
+		wantIndex []int
+	}{
+		{
+			name: "duplicates of an earlier URL's template are skipped",
+			wantUrls: []string{
+				"https://example.com/a?q=p1", "https://example.com/a?q=p2",
+				"https://example.com/a?q=p1&r=1", "https://example.com/a?q=p2&r=1",
+				"https://example.com/a?q=1&r=p1", "https://example.com/a?q=1&r=p2",
+				"https://example.com/b?q=p1", "https://example.com/b?q=p2",
+			},
+			wantIndex: []int{0, 0, 2, 2, 2, 2, 3, 3},
+		},
+		{
+			name: "skipping counts only the injections that are not duplicates",
+			skip: 5,
+			wantUrls: []string{
+				"https://example.com/a?q=1&r=p2",
+				"https://example.com/b?q=p1", "https://example.com/b?q=p2",
+			},
+			wantIndex: []int{2, 3, 3},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var urls []string
+			var urlIndexes []int
+			index := test.skip
+			for target := range gs.Targets(context.Background(), test.skip) {
+				if target.Index != index {
+					t.Errorf("target %s has index %d, want %d", target.Injection.Url, target.Index, index)
+				}
+				index++
+				urls = append(urls, target.Injection.Url)
+				urlIndexes = append(urlIndexes, target.UrlIndex)
+			}
+
+			if !reflect.DeepEqual(urls, test.wantUrls) {
+				t.Errorf("Targets() =\n%v\nwant\n%v", urls, test.wantUrls)
+			}
+			if !reflect.DeepEqual(urlIndexes, test.wantIndex) {
+				t.Errorf("Targets() URL indexes = %v, want %v", urlIndexes, test.wantIndex)
+			}
+		})
+	}
+}
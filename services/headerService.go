@@ -0,0 +1,255 @@
+package services
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+	"xss/utils"
+
+	"github.com/playwright-community/playwright-go"
+)
+
+// HeaderPoint is a header or cookie whose `-H` value holds a `{payload}` marker
+type HeaderPoint struct {
+	Location string
+	Name     string
+	Template string
+}
+
+type HeaderService struct {
+	Headers map[string]string
+	Cookies map[string]string
+	Points  []HeaderPoint
+
+	argService      *ArgsService
+	scopeService    *ScopeService
+	resourceService *ResourceService
+}
+
+var headerServiceInstance *HeaderService = nil
+
+// Singleton instance of HeaderService
+func GetHeaderService() (*HeaderService, error) {
+	if headerServiceInstance == nil {
+		argService, err := GetArgsService()
+		if err != nil {
+			return nil, err
+		}
+
+		scopeService, err := GetScopeService()
+		if err != nil {
+			return nil, err
+		}
+
+		resourceService, err := GetResourceService()
+		if err != nil {
+			return nil, err
+		}
+
+		headerServiceInstance = &HeaderService{
+			Headers:         make(map[string]string),
+			Cookies:         make(map[string]string),
+			argService:      argService,
+			scopeService:    scopeService,
+			resourceService: resourceService,
+		}
+	}
+
+	return headerServiceInstance, nil
+}
+
+// Run parses the `-H Name: value` arguments into static headers and injection points.
+// A Cookie header is split into cookies so each one can be an injection point on its own, and so the others
+// are only sent to the hosts they are set for.
+func (hs *HeaderService) Run() error {
+	specs, _ := hs.argService.GetAll()[ArgKeys.Header].([]string)
+
+	for _, spec := range specs {
+		name, value, ok := strings.Cut(spec, ":")
+		name = strings.TrimSpace(name)
+		if !ok || name == "" {
+			return fmt.Errorf("invalid header %q, expected Name: value", spec)
+		}
+		value = strings.TrimSpace(value)
+
+		if strings.EqualFold(name, "Cookie") {
+			for _, pair := range strings.Split(value, ";") {
+				cookieName, cookieValue, _ := strings.Cut(strings.TrimSpace(pair), "=")
+				if cookieName == "" {
+					continue
+				}
+				if strings.Contains(cookieValue, "{payload}") {
+					hs.Points = append(hs.Points, HeaderPoint{Location: "cookie", Name: cookieName, Template: cookieValue})
+				} else {
+					hs.Cookies[cookieName] = cookieValue
+				}
+			}
+			continue
+		}
+
+		if strings.Contains(value, "{payload}") {
+			location := "header"
+			if strings.EqualFold(name, "Referer") {
+				location = "referer"
+			}
+			hs.Points = append(hs.Points, HeaderPoint{Location: location, Name: name, Template: value})
+			continue
+		}
+
+		hs.Headers[name] = value
+	}
+
+	if len(hs.Headers) > 0 || len(hs.Points) > 0 {
+		utils.Log.Info(fmt.Sprintf("Headers parsed: [%d], header injection points: [%d]", len(hs.Headers), len(hs.Points)))
+	}
+
+	return nil
+}
+
+// Apply adds the static headers to the first party requests of a browser context, and sets the static
+// cookies for every target URL
+func (hs *HeaderService) Apply(ctx playwright.BrowserContext, urls []string) error {
+	if err := hs.RouteHeaders(ctx, hs.Headers); err != nil {
+		return err
+	}
+
+	for _, rawUrl := range urls {
+		if err := hs.AddCookies(ctx, rawUrl, hs.Cookies); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// router is a browser context or a page
+type router interface {
+	Route(url interface{}, handler func(playwright.Route), times ...int) error
+}
+
+// RouteHeaders adds the headers to every request of the context or page going to an in scope site of the
+// targets, requests to any other host go out without them so credentials don't leak to third parties.
+// The request is sent right away, a later handler falling back would drop the headers again, so requests
+// the scope or resource rules block are left to their handlers instead.
+func (hs *HeaderService) RouteHeaders(target router, headers map[string]string) error {
+	if len(headers) == 0 {
+		return nil
+	}
+
+	return target.Route("**/*", func(route playwright.Route) {
+		request := route.Request()
+		if hs.scopeService.Block(request.URL()) != "" || !hs.scopeService.FirstParty(request.URL()) || hs.resourceService.Blocked(request) {
+			route.Fallback()
+			return
+		}
+
+		merged := request.Headers()
+		for name, value := range headers {
+			merged[strings.ToLower(name)] = value
+		}
+
+		route.Continue(playwright.RouteContinueOptions{Headers: merged})
+	})
+}
+
+// AddCookies sets the cookies for the URL in the context
+func (hs *HeaderService) AddCookies(ctx playwright.BrowserContext, rawUrl string, cookies map[string]string) error {
+	if len(cookies) == 0 {
+		return nil
+	}
+
+	parsedUrl, err := url.Parse(StripInjectionPoints(rawUrl))
+	if err != nil {
+		return err
+	}
+	cookieUrl := parsedUrl.Scheme + "://" + parsedUrl.Host + "/"
+
+	var optionalCookies []playwright.OptionalCookie
+	for name, value := range cookies {
+		optionalCookies = append(optionalCookies, playwright.OptionalCookie{
+			Name:  name,
+			Value: value,
+			URL:   playwright.String(cookieUrl),
+		})
+	}
+
+	return ctx.AddCookies(optionalCookies)
+}
+
+// CombineHeadersWithPayload generates an injection per header and cookie injection point of the URL
+func (hs *HeaderService) CombineHeadersWithPayload(rawUrl string, payloads []string) []Injection {
+	combinedUrls := []Injection{}
+	rawUrl = StripInjectionPoints(rawUrl)
+
+	for _, point := range hs.Points {
+		for _, payload := range payloads {
+			injection := Injection{Url: rawUrl, Location: point.Location, Param: point.Name}
+
+			if point.Location == "cookie" {
+				// cookie values end at ; and whitespace, such payloads go in percent encoded
+				if strings.ContainsAny(payload, "; ,\"\\\t") {
+					payload = url.QueryEscape(payload)
+				}
+				injection.Cookies = map[string]string{point.Name: strings.ReplaceAll(point.Template, "{payload}", payload)}
+			} else {
+				injection.Headers = map[string]string{point.Name: strings.ReplaceAll(point.Template, "{payload}", payload)}
+			}
+
+			combinedUrls = append(combinedUrls, injection)
+		}
+	}
+
+	return combinedUrls
+}
+
+// CookieContext clones the context with its storage state into a fresh one holding the injected cookies,
+// cookies are shared by every page of a context so concurrent injections can't go into the same one
+func (hs *HeaderService) CookieContext(ctx playwright.BrowserContext, rawUrl string, cookies map[string]string) (playwright.BrowserContext, error) {
+	state, err := ctx.StorageState()
+	if err != nil {
+		return nil, err
+	}
+
+	cookieCtx, err := ctx.Browser().NewContext(playwright.BrowserNewContextOptions{StorageState: state.ToOptionalStorageState()})
+	if err != nil {
+		return nil, err
+	}
+
+	// the static cookies came along with the storage state
+	if err := hs.Apply(cookieCtx, nil); err != nil {
+		cookieCtx.Close()
+		return nil, err
+	}
+
+	if err := hs.scopeService.Apply(cookieCtx); err != nil {
+		cookieCtx.Close()
+		return nil, err
+	}
+
+	if err := hs.resourceService.Apply(cookieCtx); err != nil {
+		cookieCtx.Close()
+		return nil, err
+	}
+
+	if err := hs.AddCookies(cookieCtx, rawUrl, cookies); err != nil {
+		cookieCtx.Close()
+		return nil, err
+	}
+
+	return cookieCtx, nil
+}
+
+// InjectionKey identifies an injection by its URL and the headers and cookies sent with it
+func InjectionKey(injection Injection) string {
+	var parts []string
+	for name, value := range injection.Headers {
+		parts = append(parts, "h "+name+": "+value)
+	}
+	for name, value := range injection.Cookies {
+		parts = append(parts, "c "+name+"="+value)
+	}
+	sort.Strings(parts)
+
+	return injection.Url + "\n" + strings.Join(parts, "\n")
+}
@@ -0,0 +1,251 @@
+package services
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+	"xss/utils"
+
+	"github.com/playwright-community/playwright-go"
+)
+
+// Identity is a named browser context, logged in as one role of the target application
+type Identity struct {
+	Name string
+	Ctx  playwright.BrowserContext
+}
+
+type IdentityService struct {
+	Identities []Identity
+
+	argService  *ArgsService
+	fileService *FileService
+	injector    int
+}
+
+var identityServiceInstance *IdentityService = nil
+
+// Singleton instance of IdentityService
+func GetIdentityService() (*IdentityService, error) {
+	if identityServiceInstance == nil {
+		var argService *ArgsService
+		var fileService *FileService
+		var err error
+
+		if argService, err = GetArgsService(); err != nil {
+			return nil, err
+		}
+
+		if fileService, err = GetFileService(); err != nil {
+			return nil, err
+		}
+
+		identityServiceInstance = &IdentityService{
+			argService:  argService,
+			fileService: fileService,
+		}
+	}
+
+	return identityServiceInstance, nil
+}
+
+// Run creates a browser context per configured identity, or a single anonymous one when none are given.
+// An identity is `name=state.json` for a Playwright storage-state file or `name=login.js` for a login script.
+func (is *IdentityService) Run(browser playwright.Browser) error {
+	options := is.argService.GetAll()
+
+	specs, _ := options[ArgKeys.Identity].([]string)
+	if len(specs) == 0 {
+		ctx, err := browser.NewContext()
+		if err != nil {
+			return err
+		}
+
+		is.Identities = []Identity{{Name: "", Ctx: ctx}}
+		return nil
+	}
+
+	utils.Log.Info("Running IdentityService")
+
+	for _, spec := range specs {
+		name, path, ok := strings.Cut(spec, "=")
+		if !ok || name == "" || path == "" {
+			return fmt.Errorf("invalid identity %q, expected name=path", spec)
+		}
+
+		var ctx playwright.BrowserContext
+		var err error
+
+		if strings.EqualFold(filepath.Ext(path), ".json") {
+			ctx, err = browser.NewContext(playwright.BrowserNewContextOptions{StorageStatePath: playwright.String(path)})
+		} else {
+			ctx, err = is.Login(browser, path)
+		}
+
+		if err != nil {
+			return fmt.Errorf("identity %s: %w", name, err)
+		}
+
+		is.Identities = append(is.Identities, Identity{Name: name, Ctx: ctx})
+		utils.Log.Info(fmt.Sprintf("Identity ready: %s", name))
+	}
+
+	injectAs, _ := options[ArgKeys.InjectAs].(string)
+	if injectAs != "" {
+		is.injector = -1
+		for ix, identity := range is.Identities {
+			if identity.Name == injectAs {
+				is.injector = ix
+			}
+		}
+		if is.injector == -1 {
+			return fmt.Errorf("unknown identity %q for --inject-as", injectAs)
+		}
+	}
+
+	utils.Log.Info(fmt.Sprintf("Injecting as %s, verifying as %d identities", is.Injector().Name, len(is.Verifiers())))
+	return nil
+}
+
+// loginCheck confirms a login script logged in, by the URL the page ends on or an element only shown to
+// logged in users. Without either, the login form must be gone: no password field is visible anymore.
+type loginCheck struct {
+	url      *regexp.Regexp
+	selector string
+}
+
+// Login runs a login script in a fresh context. The first line of the script is a comment holding the
+// login page URL (`// https://app/login`), optionally followed by `// success-url: <regex>` or
+// `// success-selector: <css>` lines confirming the login. The rest is evaluated in that page, cookies stay
+// in the context.
+func (is *IdentityService) Login(browser playwright.Browser, path string) (_ playwright.BrowserContext, err error) {
+	options := is.argService.GetAll()
+	timeout := float64(options[ArgKeys.Timeout].(int))
+
+	content, err := is.fileService.ReadFileAsString(path)
+	if err != nil {
+		return nil, err
+	}
+
+	firstLine, script, _ := strings.Cut(content, "\n")
+	loginUrl := strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(firstLine), "//"))
+	if !strings.HasPrefix(loginUrl, "http") {
+		return nil, fmt.Errorf("login script %s must start with a `// <login url>` line", path)
+	}
+
+	var check loginCheck
+	for _, line := range strings.Split(script, "\n") {
+		line = strings.TrimSpace(line)
+		if !strings.HasPrefix(line, "//") {
+			break
+		}
+		name, value, _ := strings.Cut(strings.TrimSpace(strings.TrimPrefix(line, "//")), ":")
+		switch strings.TrimSpace(name) {
+		case "success-url":
+			if check.url, err = regexp.Compile(strings.TrimSpace(value)); err != nil {
+				return nil, fmt.Errorf("login script %s: invalid success-url: %w", path, err)
+			}
+		case "success-selector":
+			check.selector = strings.TrimSpace(value)
+		}
+	}
+
+	ctx, err := browser.NewContext()
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err != nil {
+			ctx.Close()
+		}
+	}()
+
+	page, err := ctx.NewPage()
+	if err != nil {
+		return nil, err
+	}
+	defer page.Close()
+
+	if _, err = page.Goto(loginUrl, playwright.PageGotoOptions{Timeout: playwright.Float(timeout)}); err != nil {
+		return nil, err
+	}
+
+	// the script usually submits the login form, the navigation tears down the evaluation and fails it,
+	// so whether it logged in is told by the page it leaves behind
+	_, scriptErr := page.Evaluate(fmt.Sprintf("async () => {\n%s\n}", script))
+
+	page.WaitForLoadState(playwright.PageWaitForLoadStateOptions{
+		State:   playwright.LoadStateNetworkidle,
+		Timeout: playwright.Float(timeout),
+	})
+	time.Sleep(500 * time.Millisecond)
+
+	if err = is.checkLogin(page, check, timeout); err != nil {
+		if scriptErr != nil {
+			err = fmt.Errorf("%w, login script error: %s", err, scriptErr)
+		}
+		return nil, err
+	}
+
+	return ctx, nil
+}
+
+// checkLogin returns an error when the page the login script left behind doesn't look logged in
+func (is *IdentityService) checkLogin(page playwright.Page, check loginCheck, timeout float64) error {
+	if check.url != nil && !check.url.MatchString(page.URL()) {
+		return fmt.Errorf("login failed, ended on %s not matching success-url", page.URL())
+	}
+
+	if check.selector != "" {
+		if err := page.Locator(check.selector).First().WaitFor(playwright.LocatorWaitForOptions{
+			State:   playwright.WaitForSelectorStateVisible,
+			Timeout: playwright.Float(timeout),
+		}); err != nil {
+			return fmt.Errorf("login failed, success-selector %s not found on %s", check.selector, page.URL())
+		}
+	}
+
+	if check.url == nil && check.selector == "" {
+		visible, err := page.Locator("input[type=password]:visible").Count()
+		if err != nil {
+			return err
+		}
+		if visible > 0 {
+			return fmt.Errorf("login failed, %s still shows a password field", page.URL())
+		}
+	}
+
+	return nil
+}
+
+// Injector returns the identity the payloads are injected as
+func (is *IdentityService) Injector() Identity {
+	return is.Identities[is.injector]
+}
+
+// Verifiers returns the identities that look for stored payloads, every other role or the injector when alone
+func (is *IdentityService) Verifiers() []Identity {
+	if len(is.Identities) < 2 {
+		return is.Identities
+	}
+
+	var verifiers []Identity
+	for ix, identity := range is.Identities {
+		if ix != is.injector {
+			verifiers = append(verifiers, identity)
+		}
+	}
+	return verifiers
+}
+
+// Close closes every identity context
+func (is *IdentityService) Close() error {
+	for _, identity := range is.Identities {
+		if err := identity.Ctx.Close(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,747 @@
+package services
+
+import (
+	"encoding/base64"
+	"encoding/xml"
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"sort"
+	"strings"
+	"xss/utils"
+)
+
+// Input formats of the -u argument
+const (
+	FormatAuto    = "auto"
+	FormatUrls    = "urls"
+	FormatRequest = "request"
+	FormatHar     = "har"
+	FormatOpenApi = "openapi"
+	FormatCurl    = "curl"
+	FormatBurp    = "burp"
+	FormatZap     = "zap"
+)
+
+// browserControlledHeaders are left to the browser or the HTTP client when replaying an imported request
+var browserControlledHeaders = map[string]struct{}{
+	"host": {}, "content-length": {}, "connection": {}, "keep-alive": {}, "transfer-encoding": {},
+	"upgrade": {}, "te": {}, "accept-encoding": {}, "proxy-connection": {},
+}
+
+// harFile is the part of the HAR 1.2 format the importer reads
+type harFile struct {
+	Log struct {
+		Entries []struct {
+			Request struct {
+				Method      string         `json:"method"`
+				Url         string         `json:"url"`
+				HttpVersion string         `json:"httpVersion"`
+				Headers     []harNameValue `json:"headers"`
+				Cookies     []harNameValue `json:"cookies"`
+				PostData    *struct {
+					MimeType string `json:"mimeType"`
+					Text     string `json:"text"`
+					Params   []struct {
+						Name        string `json:"name"`
+						Value       string `json:"value"`
+						FileName    string `json:"fileName"`
+						ContentType string `json:"contentType"`
+					} `json:"params"`
+				} `json:"postData"`
+			} `json:"request"`
+		} `json:"entries"`
+	} `json:"log"`
+}
+
+type harNameValue struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// burpItems is the Burp Suite "Save items" export, requests are base64 encoded unless saved as text
+type burpItems struct {
+	Items []struct {
+		Host     string `xml:"host"`
+		Port     string `xml:"port"`
+		Protocol string `xml:"protocol"`
+		Url      string `xml:"url"`
+		Request  struct {
+			Base64  bool   `xml:"base64,attr"`
+			Content string `xml:",chardata"`
+		} `xml:"request"`
+	} `xml:"item"`
+}
+
+// zapReport is the ZAP traditional XML report, the request header and body come with the "plus" variant
+type zapReport struct {
+	Sites []struct {
+		Name   string `xml:"name,attr"`
+		Alerts []struct {
+			Instances []struct {
+				Uri           string `xml:"uri"`
+				Method        string `xml:"method"`
+				RequestHeader string `xml:"requestheader"`
+				RequestBody   string `xml:"requestbody"`
+			} `xml:"instances>instance"`
+		} `xml:"alerts>alertitem"`
+	} `xml:"site"`
+}
+
+type ImportService struct {
+	Urls     []string
+	Headers  map[string]map[string]string
+	Requests []map[string]string
+
+	argService  *ArgsService
+	fileService *FileService
+	jsonService *JsonService
+	urlService  *UrlService
+	bodyService *BodyService
+	openApi     *OpenApiService
+	curlService *CurlService
+	seen        map[string]struct{}
+	format      string
+}
+
+var importServiceInstance *ImportService = nil
+
+// Singleton instance of ImportService
+func GetImportService() (*ImportService, error) {
+	if importServiceInstance == nil {
+		var argService *ArgsService
+		var fileService *FileService
+		var jsonService *JsonService
+		var urlService *UrlService
+		var bodyService *BodyService
+		var openApi *OpenApiService
+		var curlService *CurlService
+		var err error
+
+		if argService, err = GetArgsService(); err != nil {
+			return nil, err
+		}
+
+		if fileService, err = GetFileService(); err != nil {
+			return nil, err
+		}
+
+		if jsonService, err = GetJsonService(); err != nil {
+			return nil, err
+		}
+
+		if urlService, err = GetUrlService(); err != nil {
+			return nil, err
+		}
+
+		if bodyService, err = GetBodyService(); err != nil {
+			return nil, err
+		}
+
+		if openApi, err = GetOpenApiService(); err != nil {
+			return nil, err
+		}
+
+		if curlService, err = GetCurlService(); err != nil {
+			return nil, err
+		}
+
+		importServiceInstance = &ImportService{
+			Headers:     make(map[string]map[string]string),
+			argService:  argService,
+			fileService: fileService,
+			jsonService: jsonService,
+			urlService:  urlService,
+			bodyService: bodyService,
+			openApi:     openApi,
+			curlService: curlService,
+			seen:        make(map[string]struct{}),
+		}
+	}
+
+	return importServiceInstance, nil
+}
+
+// Format returns the input format of the -u argument, detected from the file once when set to auto
+func (ims *ImportService) Format() string {
+	if ims.format == "" {
+		ims.format = ims.detectFormat()
+	}
+	return ims.format
+}
+
+func (ims *ImportService) detectFormat() string {
+	options := ims.argService.GetAll()
+
+	format, _ := options[ArgKeys.Format].(string)
+	if format != FormatAuto {
+		return format
+	}
+
+	// the first -u value decides, URLs given on the command line are a URL list
+	sources, _ := options[ArgKeys.Urls].([]string)
+	path := ""
+	if len(sources) > 0 && !ims.urlService.IsLiteralUrl(sources[0]) {
+		path = sources[0]
+	}
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".har":
+		return FormatHar
+	case ".yaml", ".yml":
+		return FormatOpenApi
+	}
+
+	// the head of the file is enough to tell most formats apart, URL lists can be huge
+	if head, err := ims.fileService.ReadHead(path, 4096); path != "" && err == nil {
+		content := string(head)
+		if ims.curlService.IsCurl(content) {
+			return FormatCurl
+		}
+
+		// the root element tells the proxy exports apart
+		if strings.Contains(content, "<items") && strings.Contains(content, "burpVersion") {
+			return FormatBurp
+		}
+		if strings.Contains(content, "<OWASPZAPReport") {
+			return FormatZap
+		}
+
+		if ims.openApi.IsSpec(content) {
+			return FormatOpenApi
+		}
+
+		// a JSON document with a top-level log is a HAR whatever its extension
+		for _, key := range ims.jsonService.TopLevelKeys(content) {
+			if key == "log" {
+				return FormatHar
+			}
+		}
+	}
+
+	if strings.ToUpper(options[ArgKeys.Method].(string)) == "GET" {
+		return FormatUrls
+	}
+	return FormatRequest
+}
+
+// Imported reports whether the targets come from an imported file rather than a URL list or request files
+func (ims *ImportService) Imported() bool {
+	format := ims.Format()
+	return format != FormatUrls && format != FormatRequest
+}
+
+// Run reads the -u files in their format and splits the requests into GET targets and the rest
+func (ims *ImportService) Run() error {
+	utils.Log.Info("Running ImportService")
+
+	sources, err := ims.argService.Get(ArgKeys.Urls)
+	if err != nil {
+		return err
+	}
+
+	var files []string
+	for _, source := range sources.([]string) {
+		expanded, err := ims.fileService.ExpandPath(source)
+		if err != nil {
+			return err
+		}
+		files = append(files, expanded...)
+	}
+
+	format := ims.Format()
+	for _, file := range files {
+		content, err := ims.fileService.ReadFileAsString(file)
+		if err != nil {
+			return err
+		}
+
+		switch format {
+		case FormatHar:
+			err = ims.ImportHar(content)
+		case FormatOpenApi:
+			err = ims.ImportOpenApi(content)
+		case FormatCurl:
+			err = ims.ImportCurl(content, ims.dir(file))
+		case FormatBurp:
+			err = ims.ImportBurp(content)
+		case FormatZap:
+			err = ims.ImportZap(content)
+		default:
+			err = fmt.Errorf("unknown input format %q", format)
+		}
+
+		if err != nil {
+			return fmt.Errorf("%s: %w", file, err)
+		}
+	}
+
+	utils.Log.Info(fmt.Sprintf("Imported [%d] GET targets and [%d] requests", len(ims.Urls), len(ims.Requests)))
+	return nil
+}
+
+// ImportHar adds every request of a HAR file
+func (ims *ImportService) ImportHar(content string) error {
+	var har harFile
+	if err := ims.jsonService.JsonToStruct(content, &har); err != nil {
+		return err
+	}
+
+	for _, entry := range har.Log.Entries {
+		harRequest := entry.Request
+
+		request, err := ims.NewRequest(harRequest.Method, harRequest.Url)
+		if err != nil {
+			utils.Log.Warn(fmt.Sprintf("Skipping HAR entry %s %s: %s", harRequest.Method, harRequest.Url, err))
+			continue
+		}
+		if harRequest.HttpVersion != "" {
+			request["_ PROTOCOL"] = harRequest.HttpVersion
+		}
+
+		for _, header := range harRequest.Headers {
+			ims.AddHeader(request, header.Name, header.Value)
+		}
+
+		// the cookies list is complete even when the exporter stripped the Cookie header
+		if key, _ := HeaderValue(request, "Cookie"); key == "" && len(harRequest.Cookies) > 0 {
+			var pairs []string
+			for _, cookie := range harRequest.Cookies {
+				pairs = append(pairs, cookie.Name+"="+cookie.Value)
+			}
+			request["Cookie"] = strings.Join(pairs, "; ")
+		}
+
+		if postData := harRequest.PostData; postData != nil {
+			if _, contentType := HeaderValue(request, "Content-Type"); contentType == "" && postData.MimeType != "" {
+				request["Content-Type"] = postData.MimeType
+			}
+
+			request["_ BODY"] = postData.Text
+			if postData.Text == "" && len(postData.Params) > 0 {
+				// some exporters only keep the parsed parameters of form bodies
+				var parts []formPart
+				for _, param := range postData.Params {
+					parts = append(parts, formPart{Name: param.Name, FileName: param.FileName, ContentType: param.ContentType, Content: []byte(param.Value)})
+				}
+				if err := ims.SetFormBody(request, parts); err != nil {
+					utils.Log.Warn(fmt.Sprintf("Skipping HAR entry %s %s: %s", harRequest.Method, harRequest.Url, err))
+					continue
+				}
+			}
+		}
+
+		ims.Add(request)
+	}
+
+	return nil
+}
+
+// ImportOpenApi adds a request for every operation of an OpenAPI or Swagger specification
+func (ims *ImportService) ImportOpenApi(content string) error {
+	options := ims.argService.GetAll()
+	server, _ := options[ArgKeys.Server].(string)
+	methods, _ := options[ArgKeys.Methods].([]string)
+	verify, _ := options[ArgKeys.Verify].(string)
+
+	// an operation returning data is only worth scanning when a verification page shows the data
+	requests, err := ims.openApi.Requests(content, server, methods, verify != "")
+	if err != nil {
+		return err
+	}
+
+	for _, request := range requests {
+		ims.Add(request)
+	}
+	return nil
+}
+
+// dir returns the directory of an imported file, empty for the standard input
+func (ims *ImportService) dir(file string) string {
+	if file == StdinPath {
+		return ""
+	}
+	return filepath.Dir(file)
+}
+
+// ImportCurl adds the request of every curl command, one command per line or continued with a backslash.
+// Files the commands read are relative to dir.
+func (ims *ImportService) ImportCurl(content string, dir string) error {
+	commands, err := ims.curlService.Commands(content)
+	if err != nil {
+		return err
+	}
+
+	for _, args := range commands {
+		command, err := ims.curlService.Parse(args, dir)
+		if err != nil {
+			utils.Log.Warn(fmt.Sprintf("Skipping curl command %s: %s", strings.Join(args, " "), err))
+			continue
+		}
+
+		request, err := ims.CurlRequest(command)
+		if err != nil {
+			utils.Log.Warn(fmt.Sprintf("Skipping curl command %s: %s", command.Url, err))
+			continue
+		}
+
+		ims.Add(request)
+	}
+
+	return nil
+}
+
+// CurlRequest builds the request a parsed curl command sends, with curl's defaults for the method,
+// the scheme and the body content type
+func (ims *ImportService) CurlRequest(command *curlCommand) (map[string]string, error) {
+	rawUrl := command.Url
+	if !strings.Contains(rawUrl, "://") {
+		rawUrl = "http://" + rawUrl
+	}
+
+	data := strings.Join(command.Data, "&")
+	method := "GET"
+	switch {
+	case command.Head:
+		method = "HEAD"
+	case command.Get:
+		// -G sends the data in the query string
+		if data != "" {
+			separator := "?"
+			if strings.Contains(rawUrl, "?") {
+				separator = "&"
+			}
+			rawUrl += separator + data
+			data = ""
+		}
+	case len(command.Data) > 0 || len(command.Parts) > 0:
+		method = "POST"
+	}
+	if command.Method != "" {
+		method = command.Method
+	}
+
+	request, err := ims.NewRequest(method, rawUrl)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, header := range command.Headers {
+		name, value, found := strings.Cut(header, ":")
+		if !found {
+			// "Name;" sends the header empty, anything else is not a header
+			if name, found = strings.CutSuffix(header, ";"); !found {
+				continue
+			}
+		} else if value = strings.TrimSpace(value); value == "" {
+			// "Name:" removes a header curl would send
+			continue
+		}
+		ims.AddHeader(request, strings.TrimSpace(name), value)
+	}
+
+	for _, cookies := range command.Cookies {
+		ims.AddHeader(request, "Cookie", cookies)
+	}
+
+	if key, _ := HeaderValue(request, "Authorization"); key == "" {
+		if command.User != "" {
+			request["Authorization"] = "Basic " + base64.StdEncoding.EncodeToString([]byte(command.User))
+		} else if command.Bearer != "" {
+			request["Authorization"] = "Bearer " + command.Bearer
+		}
+	}
+
+	if key, _ := HeaderValue(request, "Accept-Encoding"); key == "" && command.Compressed {
+		request["Accept-Encoding"] = "deflate, gzip, br, zstd"
+	}
+
+	if command.Json {
+		for name, value := range map[string]string{"Content-Type": "application/json", "Accept": "application/json"} {
+			if key, _ := HeaderValue(request, name); key == "" {
+				request[name] = value
+			}
+		}
+	}
+
+	if len(command.Parts) > 0 {
+		if key, contentType := HeaderValue(request, "Content-Type"); !strings.Contains(strings.ToLower(contentType), "multipart/form-data") {
+			if key == "" {
+				key = "Content-Type"
+			}
+			request[key] = "multipart/form-data"
+		}
+		return request, ims.SetFormBody(request, command.Parts)
+	}
+
+	if data != "" {
+		request["_ BODY"] = data
+		if key, _ := HeaderValue(request, "Content-Type"); key == "" {
+			request["Content-Type"] = "application/x-www-form-urlencoded"
+		}
+	}
+
+	return request, nil
+}
+
+// ImportBurp adds the request of every item of a Burp Suite "Save items" export
+func (ims *ImportService) ImportBurp(content string) error {
+	var items burpItems
+	if err := xml.Unmarshal([]byte(content), &items); err != nil {
+		return err
+	}
+
+	for _, item := range items.Items {
+		raw := item.Request.Content
+		if item.Request.Base64 {
+			decoded, err := base64.StdEncoding.DecodeString(strings.TrimSpace(raw))
+			if err != nil {
+				utils.Log.Warn(fmt.Sprintf("Skipping Burp item %s: %s", item.Url, err))
+				continue
+			}
+			raw = string(decoded)
+		}
+
+		host := item.Host
+		if item.Port != "" && !(item.Protocol == "https" && item.Port == "443") && !(item.Protocol == "http" && item.Port == "80") {
+			host += ":" + item.Port
+		}
+
+		if err := ims.ImportRaw(raw, item.Protocol, host); err != nil {
+			utils.Log.Warn(fmt.Sprintf("Skipping Burp item %s: %s", item.Url, err))
+		}
+	}
+
+	return nil
+}
+
+// ImportZap adds the request of every alert instance of a ZAP XML report, rebuilt from the method and URI
+// when the report does not hold the request itself. The URI gives the scheme and host of a recorded request.
+func (ims *ImportService) ImportZap(content string) error {
+	var report zapReport
+	if err := xml.Unmarshal([]byte(content), &report); err != nil {
+		return err
+	}
+
+	for _, site := range report.Sites {
+		for _, alert := range site.Alerts {
+			for _, instance := range alert.Instances {
+				if strings.TrimSpace(instance.RequestHeader) != "" {
+					// the URI completes an origin-form request header like the Burp item protocol and host do
+					scheme, host := "", ""
+					if uri, err := url.Parse(instance.Uri); err == nil {
+						scheme, host = uri.Scheme, uri.Host
+						if port := uri.Port(); (scheme == "https" && port == "443") || (scheme == "http" && port == "80") {
+							host = uri.Hostname()
+						}
+					}
+
+					raw := strings.TrimRight(instance.RequestHeader, "\r\n") + "\r\n\r\n" + instance.RequestBody
+					if err := ims.ImportRaw(raw, scheme, host); err != nil {
+						utils.Log.Warn(fmt.Sprintf("Skipping ZAP instance %s %s: %s", instance.Method, instance.Uri, err))
+					}
+					continue
+				}
+
+				request, err := ims.NewRequest(instance.Method, instance.Uri)
+				if err != nil {
+					utils.Log.Warn(fmt.Sprintf("Skipping ZAP instance %s %s: %s", instance.Method, instance.Uri, err))
+					continue
+				}
+				ims.Add(request)
+			}
+		}
+	}
+
+	return nil
+}
+
+// ImportRaw parses a raw HTTP request captured by a proxy, the scheme and host complete an origin-form request
+func (ims *ImportService) ImportRaw(raw string, scheme string, host string) error {
+	request, err := ims.urlService.ParseRequest(raw)
+	if err != nil {
+		return err
+	}
+
+	if request["_ SCHEME"] == "" {
+		if scheme == "" {
+			scheme = "https"
+		}
+		request["_ SCHEME"] = strings.ToLower(scheme)
+	}
+	if key, _ := HeaderValue(request, "Host"); key == "" {
+		if host == "" {
+			return fmt.Errorf("request without a host")
+		}
+		request["Host"] = host
+	}
+
+	ims.Add(request)
+	return nil
+}
+
+// NewRequest starts a request map from a method and an absolute URL
+func (ims *ImportService) NewRequest(method string, rawUrl string) (map[string]string, error) {
+	parsedUrl, err := url.Parse(rawUrl)
+	if err != nil {
+		return nil, err
+	}
+	if parsedUrl.Host == "" {
+		return nil, fmt.Errorf("URL without a host")
+	}
+
+	return map[string]string{
+		"_ METHOD":   strings.ToUpper(method),
+		"_ PATH":     parsedUrl.RequestURI(),
+		"_ SCHEME":   parsedUrl.Scheme,
+		"_ PROTOCOL": "HTTP/1.1",
+		"_ BODY":     "",
+		"Host":       parsedUrl.Host,
+	}, nil
+}
+
+// AddHeader adds an imported header, repeated headers are joined like in request files
+func (ims *ImportService) AddHeader(request map[string]string, name string, value string) {
+	if strings.HasPrefix(name, ":") || strings.EqualFold(name, "Host") {
+		return
+	}
+
+	if key, existing := HeaderValue(request, name); key != "" {
+		separator := "\n"
+		if strings.EqualFold(name, "Cookie") {
+			separator = "; "
+		}
+		request[key] = existing + separator + value
+		return
+	}
+	request[name] = value
+}
+
+// SetFormBody encodes parsed form parameters as the request's urlencoded or multipart body
+func (ims *ImportService) SetFormBody(request map[string]string, parts []formPart) error {
+	key, contentType := HeaderValue(request, "Content-Type")
+	if key == "" {
+		key = "Content-Type"
+	}
+
+	if strings.Contains(strings.ToLower(contentType), "multipart/form-data") {
+		body, multipartType, err := ims.bodyService.BuildMultipart(parts)
+		if err != nil {
+			return err
+		}
+		request["_ BODY"] = body
+		request[key] = multipartType
+		return nil
+	}
+
+	values := url.Values{}
+	for _, part := range parts {
+		values.Add(part.Name, string(part.Content))
+	}
+	request["_ BODY"] = values.Encode()
+	if contentType == "" {
+		request[key] = "application/x-www-form-urlencoded"
+	}
+	return nil
+}
+
+// Add keeps an imported request when it is in scope and not a duplicate, GET requests become URL targets
+func (ims *ImportService) Add(request map[string]string) {
+	_, host := HeaderValue(request, "Host")
+	if !ims.InScope(host) {
+		return
+	}
+
+	signature := ims.Signature(request)
+	if _, exists := ims.seen[signature]; exists {
+		return
+	}
+	ims.seen[signature] = struct{}{}
+
+	if request["_ METHOD"] == "GET" {
+		target := request["_ SCHEME"] + "://" + host + request["_ PATH"]
+
+		headers := make(map[string]string)
+		for name, value := range request {
+			if _, controlled := browserControlledHeaders[strings.ToLower(name)]; !controlled && !strings.HasPrefix(name, "_ ") {
+				// the browser sends repeated headers as one comma separated header
+				headers[name] = strings.ReplaceAll(value, "\n", ", ")
+			}
+		}
+
+		ims.Urls = append(ims.Urls, target)
+		ims.Headers[target] = headers
+		return
+	}
+
+	if key, _ := HeaderValue(request, "Content-Length"); key != "" {
+		delete(request, key)
+	}
+	ims.Requests = append(ims.Requests, request)
+}
+
+// InScope reports whether the host matches the --import-hosts list, `*.example.com` also matches subdomains
+func (ims *ImportService) InScope(host string) bool {
+	hosts, _ := ims.argService.GetAll()[ArgKeys.Hosts].([]string)
+	if len(hosts) == 0 {
+		return true
+	}
+
+	host = strings.ToLower(host)
+	if hostname, _, found := strings.Cut(host, ":"); found {
+		host = hostname
+	}
+
+	for _, pattern := range hosts {
+		pattern = strings.ToLower(strings.TrimSpace(pattern))
+		if pattern == host {
+			return true
+		}
+		if suffix, ok := strings.CutPrefix(pattern, "*."); ok && (host == suffix || strings.HasSuffix(host, "."+suffix)) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Signature identifies a request by method, host, path and the names of its parameters, values are ignored
+func (ims *ImportService) Signature(request map[string]string) string {
+	_, host := HeaderValue(request, "Host")
+	path, query, _ := strings.Cut(request["_ PATH"], "?")
+
+	var names []string
+	if values, err := url.ParseQuery(query); err == nil {
+		for name := range values {
+			names = append(names, "q:"+name)
+		}
+	}
+
+	_, contentType := HeaderValue(request, "Content-Type")
+	contentType = strings.ToLower(contentType)
+	body := request["_ BODY"]
+
+	switch {
+	case strings.Contains(contentType, "json"):
+		if leaves, err := ims.jsonService.JsonStringLeaves(body); err == nil {
+			for _, leaf := range leaves {
+				names = append(names, "j:"+leaf.Pointer)
+			}
+		}
+	case strings.Contains(contentType, "multipart/form-data"):
+		if parts, err := ims.bodyService.ParseMultipart(body, contentType); err == nil {
+			for _, part := range parts {
+				names = append(names, "m:"+part.Name)
+			}
+		}
+	case body != "":
+		if values, err := url.ParseQuery(strings.TrimSpace(body)); err == nil {
+			for name := range values {
+				names = append(names, "b:"+name)
+			}
+		}
+	}
+
+	sort.Strings(names)
+	return strings.Join([]string{request["_ METHOD"], strings.ToLower(host), path, strings.Join(names, ",")}, " ")
+}
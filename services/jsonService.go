@@ -3,6 +3,15 @@ package services
 import (
 	"bytes"
 	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+var (
+	jsonPointerEscaper   = strings.NewReplacer("~", "~0", "/", "~1")
+	jsonPointerUnescaper = strings.NewReplacer("~1", "/", "~0", "~")
 )
 
 type JsonService struct {
@@ -89,3 +98,135 @@ func (js *JsonService) StringToArray(jsonData string) ([]string, error) {
 	}
 	return data, nil
 }
+
+// JsonLeaf is a string value inside a JSON document and its JSON pointer
+type JsonLeaf struct {
+	Pointer string
+	Value   string
+}
+
+// Decode JSON keeping numbers as written
+func (js *JsonService) decodeDocument(jsonData string) (interface{}, error) {
+	var data interface{}
+	decoder := json.NewDecoder(strings.NewReader(jsonData))
+	decoder.UseNumber()
+	if err := decoder.Decode(&data); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// TopLevelKeys returns the keys of a JSON object in order, reading only as far as the content goes so the head
+// of a large document is enough. Nothing is returned when the content isn't an object.
+func (js *JsonService) TopLevelKeys(content string) []string {
+	decoder := json.NewDecoder(strings.NewReader(content))
+	if token, err := decoder.Token(); err != nil || token != json.Delim('{') {
+		return nil
+	}
+
+	var keys []string
+	for decoder.More() {
+		token, err := decoder.Token()
+		if err != nil {
+			break
+		}
+		key, ok := token.(string)
+		if !ok {
+			break
+		}
+		keys = append(keys, key)
+
+		// a value cut off by the end of the head ends the keys
+		var value json.RawMessage
+		if err := decoder.Decode(&value); err != nil {
+			break
+		}
+	}
+	return keys
+}
+
+// Encode JSON compactly without HTML escaping, safe to call from several goroutines unlike the shared encoder
+func (js *JsonService) encodeDocument(data interface{}) (string, error) {
+	buff := bytes.Buffer{}
+	encoder := json.NewEncoder(&buff)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(data); err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(buff.String(), "\n"), nil
+}
+
+// Get every string leaf of a JSON document with its JSON pointer, object keys in sorted order
+func (js *JsonService) JsonStringLeaves(jsonData string) ([]JsonLeaf, error) {
+	data, err := js.decodeDocument(jsonData)
+	if err != nil {
+		return nil, err
+	}
+
+	var leaves []JsonLeaf
+	var walk func(node interface{}, pointer string)
+	walk = func(node interface{}, pointer string) {
+		switch value := node.(type) {
+		case map[string]interface{}:
+			keys := make([]string, 0, len(value))
+			for key := range value {
+				keys = append(keys, key)
+			}
+			sort.Strings(keys)
+			for _, key := range keys {
+				walk(value[key], pointer+"/"+jsonPointerEscaper.Replace(key))
+			}
+		case []interface{}:
+			for ix, item := range value {
+				walk(item, pointer+"/"+strconv.Itoa(ix))
+			}
+		case string:
+			leaves = append(leaves, JsonLeaf{Pointer: pointer, Value: value})
+		}
+	}
+	walk(data, "")
+
+	return leaves, nil
+}
+
+// Replace the leaf at the JSON pointer with a string and return the re-encoded document
+func (js *JsonService) JsonSetLeaf(jsonData string, pointer string, leaf string) (string, error) {
+	data, err := js.decodeDocument(jsonData)
+	if err != nil {
+		return "", err
+	}
+
+	if pointer == "" {
+		return js.encodeDocument(leaf)
+	}
+
+	tokens := strings.Split(strings.TrimPrefix(pointer, "/"), "/")
+	node := data
+	for ix, token := range tokens {
+		token = jsonPointerUnescaper.Replace(token)
+		last := ix == len(tokens)-1
+
+		switch value := node.(type) {
+		case map[string]interface{}:
+			if last {
+				value[token] = leaf
+			} else {
+				node = value[token]
+			}
+		case []interface{}:
+			index, err := strconv.Atoi(token)
+			if err != nil || index < 0 || index >= len(value) {
+				return "", fmt.Errorf("invalid JSON pointer %s", pointer)
+			}
+			if last {
+				value[index] = leaf
+			} else {
+				node = value[index]
+			}
+		default:
+			return "", fmt.Errorf("invalid JSON pointer %s", pointer)
+		}
+	}
+
+	return js.encodeDocument(data)
+}
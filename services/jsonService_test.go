@@ -0,0 +1,187 @@
+package services
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestJsonStringLeaves(t *testing.T) {
+	js, err := GetJsonService()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		document string
+		want     []JsonLeaf
+	}{
+		{
+			name:     "nested objects and arrays in key order",
+			document: `{"z":"last","a":{"b":["x",1,{"c":"y"}]},"n":null,"t":true}`,
+			want: []JsonLeaf{
+				{Pointer: "/a/b/0", Value: "x"},
+				{Pointer: "/a/b/2/c", Value: "y"},
+				{Pointer: "/z", Value: "last"},
+			},
+		},
+		{
+			name:     "keys with ~ and / are escaped",
+			document: `{"a/b":"1","m~n":"2"}`,
+			want: []JsonLeaf{
+				{Pointer: "/a~1b", Value: "1"},
+				{Pointer: "/m~0n", Value: "2"},
+			},
+		},
+		{
+			name:     "a string document is its own leaf",
+			document: `"alone"`,
+			want:     []JsonLeaf{{Pointer: "", Value: "alone"}},
+		},
+		{
+			name:     "no string leaves",
+			document: `{"a":1,"b":[true,null]}`,
+			want:     nil,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := js.JsonStringLeaves(test.document)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("JsonStringLeaves() = %v, want %v", got, test.want)
+			}
+		})
+	}
+
+	if _, err := js.JsonStringLeaves(`{"a":`); err == nil {
+		t.Error("JsonStringLeaves() of truncated JSON expected an error")
+	}
+}
+
+func TestJsonSetLeaf(t *testing.T) {
+	js, err := GetJsonService()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		document string
+		pointer  string
+		leaf     string
+		want     string
+	}{
+		{
+			name:     "numbers keep their exact form and HTML is not escaped",
+			document: `{"id":12345678901234567890,"price":1.50,"q":"old"}`,
+			pointer:  "/q",
+			leaf:     `<svg onload=alert(1)>`,
+			want:     `{"id":12345678901234567890,"price":1.50,"q":"<svg onload=alert(1)>"}`,
+		},
+		{
+			name:     "array element inside an object",
+			document: `{"a":{"b":["x","y"]}}`,
+			pointer:  "/a/b/1",
+			leaf:     `"quoted"`,
+			want:     `{"a":{"b":["x","\"quoted\""]}}`,
+		},
+		{
+			name:     "escaped keys",
+			document: `{"a/b":{"m~n":"v"}}`,
+			pointer:  "/a~1b/m~0n",
+			leaf:     "P",
+			want:     `{"a/b":{"m~n":"P"}}`,
+		},
+		{
+			name:     "the root",
+			document: `"old"`,
+			pointer:  "",
+			leaf:     "new",
+			want:     `"new"`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := js.JsonSetLeaf(test.document, test.pointer, test.leaf)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != test.want {
+				t.Errorf("JsonSetLeaf() = %s, want %s", got, test.want)
+			}
+		})
+	}
+
+	for _, pointer := range []string{"/a/5", "/a/x", "/a/-1", "/s/t"} {
+		if _, err := js.JsonSetLeaf(`{"a":["x"],"s":"v"}`, pointer, "P"); err == nil {
+			t.Errorf("JsonSetLeaf() at %s expected an error", pointer)
+		}
+	}
+}
+
+func TestJsonSetLeafRoundTrip(t *testing.T) {
+	js, err := GetJsonService()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	document := `{"user":{"name":"a","tags":["b","c"],"x/y":"d"},"list":[{"k":"e"}],"n":1}`
+	leaves, err := js.JsonStringLeaves(document)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// setting every leaf in turn changes only that leaf
+	for _, leaf := range leaves {
+		injected, err := js.JsonSetLeaf(document, leaf.Pointer, "PAYLOAD")
+		if err != nil {
+			t.Fatalf("JsonSetLeaf(%s): %s", leaf.Pointer, err)
+		}
+
+		got, err := js.JsonStringLeaves(injected)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(got) != len(leaves) {
+			t.Fatalf("JsonSetLeaf(%s) left %d leaves, want %d", leaf.Pointer, len(got), len(leaves))
+		}
+		for ix, other := range got {
+			want := leaves[ix].Value
+			if other.Pointer == leaf.Pointer {
+				want = "PAYLOAD"
+			}
+			if other.Pointer != leaves[ix].Pointer || other.Value != want {
+				t.Errorf("JsonSetLeaf(%s) leaf %s = %q, want %q", leaf.Pointer, other.Pointer, other.Value, want)
+			}
+		}
+	}
+}
+
+func TestTopLevelKeys(t *testing.T) {
+	js, err := GetJsonService()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		content string
+		want    []string
+	}{
+		{`{"info":{"title":"x"},"openapi":"3.0.0"}`, []string{"info", "openapi"}},
+		{`{"log":{"entries":[{"request":`, []string{"log"}},
+		{`{"a":1,"b":[1,2,`, []string{"a", "b"}},
+		{`["a","b"]`, nil},
+		{`not json`, nil},
+	}
+
+	for _, test := range tests {
+		if got := js.TopLevelKeys(test.content); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("TopLevelKeys(%s) = %v, want %v", test.content, got, test.want)
+		}
+	}
+}
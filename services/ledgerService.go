@@ -0,0 +1,122 @@
+package services
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"sync"
+)
+
+// ledgerCapacity is how many canaries fit in the 6 digits after the marker
+const ledgerCapacity = 1000000
+
+// LedgerEntry remembers which injection a canary was handed out for
+type LedgerEntry struct {
+	Canary   string
+	Target   string
+	Location string
+	Param    string
+	Role     string
+}
+
+// LedgerService hands out per-injection canaries and maps them back to their injection.
+// Canaries stay numeric and below 2^53 so `alert(###)` style payloads report them unchanged.
+type LedgerService struct {
+	base    int64
+	marker  string
+	counter int64
+	entries map[string]LedgerEntry
+	pattern *regexp.Regexp
+	m       sync.Mutex
+}
+
+var ledgerServiceInstance *LedgerService = nil
+
+// Singleton instance of LedgerService
+func GetLedgerService() (*LedgerService, error) {
+	if ledgerServiceInstance == nil {
+		ledgerServiceInstance = &LedgerService{
+			entries: make(map[string]LedgerEntry),
+		}
+	}
+
+	return ledgerServiceInstance, nil
+}
+
+// Init derives the canary range from the scan UUID, the first 10 digits are shared by every canary
+func (ls *LedgerService) Init(uuid string) error {
+	value, err := strconv.ParseInt(uuid, 10, 64)
+	if err != nil {
+		return err
+	}
+
+	ls.m.Lock()
+	defer ls.m.Unlock()
+
+	ls.base = value - value%1000000
+	ls.marker = strconv.FormatInt(ls.base, 10)[:10]
+	ls.pattern = regexp.MustCompile(ls.marker + `\d{6}`)
+
+	return nil
+}
+
+// Marker returns the prefix shared by all canaries, used by the sink monitor
+func (ls *LedgerService) Marker() string {
+	return ls.marker
+}
+
+// Issue records the injection and returns its canary, it fails once the canaries of the marker run out
+// since a larger counter would spill into the marker digits
+func (ls *LedgerService) Issue(entry LedgerEntry) (string, error) {
+	ls.m.Lock()
+	defer ls.m.Unlock()
+
+	if ls.counter+1 >= ledgerCapacity {
+		return "", fmt.Errorf("all %d canaries of the scan are issued", ledgerCapacity-1)
+	}
+
+	ls.counter++
+	entry.Canary = strconv.FormatInt(ls.base+ls.counter, 10)
+	ls.entries[entry.Canary] = entry
+
+	return entry.Canary, nil
+}
+
+// Lookup returns the injection a canary belongs to
+func (ls *LedgerService) Lookup(canary string) (LedgerEntry, bool) {
+	ls.m.Lock()
+	defer ls.m.Unlock()
+
+	entry, ok := ls.entries[canary]
+	return entry, ok
+}
+
+// Match finds every known canary inside a dialog message, sink argument or page content
+func (ls *LedgerService) Match(text string) []LedgerEntry {
+	if ls.pattern == nil {
+		return nil
+	}
+
+	var entries []LedgerEntry
+	seen := make(map[string]struct{})
+	for _, canary := range ls.pattern.FindAllString(text, -1) {
+		if _, exists := seen[canary]; exists {
+			continue
+		}
+		seen[canary] = struct{}{}
+
+		if entry, ok := ls.Lookup(canary); ok {
+			entries = append(entries, entry)
+		}
+	}
+
+	return entries
+}
+
+// Len returns how many injections were recorded
+func (ls *LedgerService) Len() int {
+	ls.m.Lock()
+	defer ls.m.Unlock()
+
+	return len(ls.entries)
+}
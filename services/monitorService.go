@@ -0,0 +1,166 @@
+package services
+
+import (
+	"fmt"
+	"time"
+	"xss/utils"
+
+	"github.com/playwright-community/playwright-go"
+)
+
+// sinkScript wraps common DOM sinks and records every call whose argument carries the marker
+const sinkScript = `(() => {
+	const marker = %q;
+	const hits = window.__xssSinks = window.__xssSinks || [];
+	const record = (sink, value) => {
+		try {
+			value = String(value);
+			if (value.includes(marker)) hits.push({ sink, value: value.slice(0, 500) });
+		} catch (e) {}
+	};
+	const wrap = (obj, name) => {
+		const original = obj && obj[name];
+		if (typeof original !== 'function') return;
+		obj[name] = function (...args) {
+			record(name, args.join(' '));
+			return original.apply(this, args);
+		};
+	};
+	const wrapSetter = (proto, prop) => {
+		const desc = Object.getOwnPropertyDescriptor(proto, prop);
+		if (!desc || !desc.set) return;
+		Object.defineProperty(proto, prop, {
+			...desc,
+			set(value) {
+				record(prop, value);
+				return desc.set.call(this, value);
+			},
+		});
+	};
+	wrap(document, 'write');
+	wrap(document, 'writeln');
+	wrap(Element.prototype, 'insertAdjacentHTML');
+	wrap(Range.prototype, 'createContextualFragment');
+	wrapSetter(Element.prototype, 'innerHTML');
+	wrapSetter(Element.prototype, 'outerHTML');
+	wrapSetter(HTMLIFrameElement.prototype, 'srcdoc');
+})();`
+
+// MonitorHit is a single dialog or sink call observed on a monitored page
+type MonitorHit struct {
+	Kind  string
+	Value string
+	Sink  bool
+}
+
+// PageMonitor watches a page for dialogs and sink calls carrying the marker
+type PageMonitor struct {
+	page         playwright.Page
+	dialogs      chan MonitorHit
+	handleDialog func(playwright.Dialog)
+	closed       bool
+}
+
+type MonitorService struct{}
+
+var monitorServiceInstance *MonitorService = nil
+
+// Singleton instance of MonitorService
+func GetMonitorService() (*MonitorService, error) {
+	if monitorServiceInstance == nil {
+		monitorServiceInstance = &MonitorService{}
+	}
+
+	return monitorServiceInstance, nil
+}
+
+// Attach installs the sink hooks and dialog listener, must be called before the page navigates
+func (ms *MonitorService) Attach(page playwright.Page, marker string) (*PageMonitor, error) {
+	script := fmt.Sprintf(sinkScript, marker)
+	if err := page.AddInitScript(playwright.Script{Content: &script}); err != nil {
+		return nil, err
+	}
+
+	monitor := &PageMonitor{
+		page:    page,
+		dialogs: make(chan MonitorHit, 16),
+	}
+
+	monitor.handleDialog = func(dialog playwright.Dialog) {
+		hit := MonitorHit{Kind: dialog.Type(), Value: dialog.Message()}
+		if err := dialog.Accept(); err != nil {
+			utils.Log.Error(fmt.Sprintf("Error accepting dialog %s: %s", hit.Kind, hit.Value))
+			return
+		}
+
+		select {
+		case monitor.dialogs <- hit:
+		default:
+			// buffer full, page keeps spamming dialogs
+		}
+	}
+	page.On("dialog", monitor.handleDialog)
+
+	return monitor, nil
+}
+
+// Wait blocks until a dialog shows up or the timeout passes, then returns every hit seen so far
+func (ms *MonitorService) Wait(monitor *PageMonitor, timeout time.Duration) []MonitorHit {
+	var hits []MonitorHit
+
+	select {
+	case hit := <-monitor.dialogs:
+		hits = append(hits, hit)
+	case <-time.After(timeout):
+	}
+
+	return append(hits, ms.Collect(monitor)...)
+}
+
+// Collect drains pending dialogs and reads the sink hits recorded by the init script
+func (ms *MonitorService) Collect(monitor *PageMonitor) []MonitorHit {
+	var hits []MonitorHit
+
+drain:
+	for {
+		select {
+		case hit := <-monitor.dialogs:
+			hits = append(hits, hit)
+		default:
+			break drain
+		}
+	}
+
+	result, err := monitor.page.Evaluate(`() => window.__xssSinks || []`)
+	if err != nil {
+		return hits
+	}
+
+	// evaluate results come back as plain maps, the shared json encoder is not safe to use from page goroutines
+	if entries, ok := result.([]interface{}); ok {
+		for _, entry := range entries {
+			if hit, ok := entry.(map[string]interface{}); ok {
+				hits = append(hits, MonitorHit{Kind: fmt.Sprint(hit["sink"]), Value: fmt.Sprint(hit["value"]), Sink: true})
+			}
+		}
+	}
+
+	// reset so the next collect on the same page only reports new calls
+	monitor.page.Evaluate(`() => { window.__xssSinks = []; }`)
+
+	return hits
+}
+
+// Reset drops every hit collected so far, used to ignore what fired before the injection step
+func (ms *MonitorService) Reset(monitor *PageMonitor) {
+	ms.Collect(monitor)
+}
+
+// Detach removes the dialog listener, the page itself is left open
+func (ms *MonitorService) Detach(monitor *PageMonitor) {
+	if monitor.closed {
+		return
+	}
+	monitor.closed = true
+	monitor.page.RemoveListener("dialog", monitor.handleDialog)
+}
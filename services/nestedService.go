@@ -0,0 +1,243 @@
+package services
+
+import (
+	"encoding/base64"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	urlutil "github.com/projectdiscovery/utils/url"
+)
+
+// maxNestingDepth stops the expansion of values encoded inside values encoded inside values
+const maxNestingDepth = 3
+
+var base64Pattern = regexp.MustCompile(`^[A-Za-z0-9+/_-]{8,}={0,2}$`)
+
+// nestedVariant is an encoded value with the payload placed in one of its leaves
+type nestedVariant struct {
+	Value string
+	Path  string
+}
+
+type NestedService struct {
+	jsonService *JsonService
+}
+
+var nestedServiceInstance *NestedService = nil
+
+// Singleton instance of NestedService
+func GetNestedService() (*NestedService, error) {
+	if nestedServiceInstance == nil {
+		jsonService, err := GetJsonService()
+		if err != nil {
+			return nil, err
+		}
+
+		nestedServiceInstance = &NestedService{
+			jsonService: jsonService,
+		}
+	}
+
+	return nestedServiceInstance, nil
+}
+
+// CombineUrlNestedWithPayload finds query values holding JSON, base64 or base64url JSON, nested URLs or
+// form encoded data, and injects into each leaf inside them, re-encoding the value the way it came in
+func (ns *NestedService) CombineUrlNestedWithPayload(rawUrl string, payloads []string) []Injection {
+	combinedUrls := []Injection{}
+
+	if strings.Contains(rawUrl, "{payload}") {
+		return combinedUrls
+	}
+
+	parsedUrl, err := urlutil.Parse(rawUrl)
+	if err != nil || parsedUrl.RawQuery == "" {
+		return combinedUrls
+	}
+
+	parsedUrl.Query().Iterate(func(key string, values []string) bool {
+		if len(values) == 0 {
+			return true
+		}
+
+		// a + is a space in a query value but part of the data in standard base64 sent unescaped
+		decoded, err := url.PathUnescape(values[0])
+		if err != nil {
+			decoded = values[0]
+		} else if !base64Pattern.MatchString(decoded) {
+			if unescaped, err := url.QueryUnescape(values[0]); err == nil {
+				decoded = unescaped
+			}
+		}
+
+		for _, payload := range payloads {
+			for _, variant := range ns.Expand(decoded, payload, 0) {
+				clonedUrl, _ := urlutil.Parse(rawUrl)
+				clonedUrl.Query().Set(key, url.QueryEscape(variant.Value))
+				combinedUrls = append(combinedUrls, Injection{
+					Url:      clonedUrl.String(),
+					Location: "nested",
+					Param:    key + " " + variant.Path,
+				})
+			}
+		}
+		return true
+	})
+
+	return combinedUrls
+}
+
+// Expand returns a variant of the value for every leaf it encodes, with the payload in that leaf
+func (ns *NestedService) Expand(value string, payload string, depth int) []nestedVariant {
+	if depth >= maxNestingDepth {
+		return nil
+	}
+
+	trimmed := strings.TrimSpace(value)
+
+	if strings.HasPrefix(trimmed, "{") || strings.HasPrefix(trimmed, "[") {
+		if variants, ok := ns.expandJson(trimmed, payload, depth); ok {
+			return variants
+		}
+	}
+
+	if parsed, err := url.Parse(trimmed); err == nil && parsed.IsAbs() && parsed.Host != "" {
+		return ns.expandUrl(parsed, payload, depth)
+	}
+
+	// padded base64 ends in = like form data does, it is tried first
+	if base64Pattern.MatchString(trimmed) {
+		if variants := ns.expandBase64(trimmed, payload, depth); len(variants) > 0 {
+			return variants
+		}
+	}
+
+	if strings.Contains(trimmed, "=") && !strings.ContainsAny(trimmed, " <>\"") {
+		return ns.expandForm(trimmed, payload, depth)
+	}
+
+	return nil
+}
+
+func (ns *NestedService) expandJson(value string, payload string, depth int) ([]nestedVariant, bool) {
+	leaves, err := ns.jsonService.JsonStringLeaves(value)
+	if err != nil {
+		return nil, false
+	}
+
+	var variants []nestedVariant
+	for _, leaf := range leaves {
+		if injected, err := ns.jsonService.JsonSetLeaf(value, leaf.Pointer, payload); err == nil {
+			variants = append(variants, nestedVariant{Value: injected, Path: "json:" + leaf.Pointer})
+		}
+
+		for _, inner := range ns.Expand(leaf.Value, payload, depth+1) {
+			if injected, err := ns.jsonService.JsonSetLeaf(value, leaf.Pointer, inner.Value); err == nil {
+				variants = append(variants, nestedVariant{Value: injected, Path: "json:" + leaf.Pointer + " > " + inner.Path})
+			}
+		}
+	}
+
+	return variants, true
+}
+
+func (ns *NestedService) expandUrl(parsed *url.URL, payload string, depth int) []nestedVariant {
+	var variants []nestedVariant
+
+	query := parsed.Query()
+	for _, key := range sortedKeys(query) {
+		values := query[key]
+		for _, leaf := range ns.leafValues(values, payload, depth) {
+			clonedQuery := url.Values{}
+			for k, v := range query {
+				clonedQuery[k] = append([]string{}, v...)
+			}
+			clonedQuery.Set(key, leaf.Value)
+
+			clonedUrl := *parsed
+			clonedUrl.RawQuery = clonedQuery.Encode()
+			variants = append(variants, nestedVariant{Value: clonedUrl.String(), Path: "url:" + key + leaf.Path})
+		}
+	}
+
+	return variants
+}
+
+func (ns *NestedService) expandForm(value string, payload string, depth int) []nestedVariant {
+	query, err := url.ParseQuery(value)
+	if err != nil || len(query) == 0 {
+		return nil
+	}
+
+	var variants []nestedVariant
+	for _, key := range sortedKeys(query) {
+		values := query[key]
+		for _, leaf := range ns.leafValues(values, payload, depth) {
+			clonedQuery := url.Values{}
+			for k, v := range query {
+				clonedQuery[k] = append([]string{}, v...)
+			}
+			clonedQuery.Set(key, leaf.Value)
+			variants = append(variants, nestedVariant{Value: clonedQuery.Encode(), Path: "form:" + key + leaf.Path})
+		}
+	}
+
+	return variants
+}
+
+// sortedKeys keeps the generated variants in a stable order between runs
+func sortedKeys(query url.Values) []string {
+	keys := make([]string, 0, len(query))
+	for key := range query {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// leafValues returns the payload itself followed by the payload nested one level deeper in the current value
+func (ns *NestedService) leafValues(values []string, payload string, depth int) []nestedVariant {
+	leaves := []nestedVariant{{Value: payload}}
+	if len(values) == 0 {
+		return leaves
+	}
+
+	for _, variant := range ns.Expand(values[0], payload, depth+1) {
+		leaves = append(leaves, nestedVariant{Value: variant.Value, Path: " > " + variant.Path})
+	}
+	return leaves
+}
+
+func (ns *NestedService) expandBase64(value string, payload string, depth int) []nestedVariant {
+	encodings := []struct {
+		name     string
+		encoding *base64.Encoding
+	}{
+		{"base64", base64.StdEncoding},
+		{"base64", base64.RawStdEncoding},
+		{"base64url", base64.URLEncoding},
+		{"base64url", base64.RawURLEncoding},
+	}
+
+	for _, candidate := range encodings {
+		decoded, err := candidate.encoding.DecodeString(value)
+		if err != nil || !utf8.Valid(decoded) {
+			continue
+		}
+
+		// only structured content is worth re-encoding, random tokens decode to garbage or plain words
+		var variants []nestedVariant
+		for _, inner := range ns.Expand(string(decoded), payload, depth+1) {
+			variants = append(variants, nestedVariant{
+				Value: candidate.encoding.EncodeToString([]byte(inner.Value)),
+				Path:  candidate.name + " > " + inner.Path,
+			})
+		}
+		return variants
+	}
+
+	return nil
+}
@@ -0,0 +1,721 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+	"xss/utils"
+
+	"gopkg.in/yaml.v3"
+)
+
+// maxRefHops bounds the chain of $ref pointers resolved for one node
+const maxRefHops = 8
+
+// openApiMethods are the operation keys of a path item in the order they are imported
+var openApiMethods = []string{"get", "put", "post", "delete", "options", "head", "patch", "trace"}
+
+// schemaFormatExamples are placeholder values for string formats without an example
+var schemaFormatExamples = map[string]string{
+	"date":      "2024-01-01",
+	"date-time": "2024-01-01T00:00:00Z",
+	"email":     "user@example.com",
+	"uuid":      "3fa85f64-5717-4562-b3fc-2c963f66afa6",
+	"uri":       "https://example.com/",
+	"url":       "https://example.com/",
+	"hostname":  "example.com",
+	"ipv4":      "127.0.0.1",
+	"ipv6":      "::1",
+	"byte":      "dGVzdA==",
+	"password":  "Password1!",
+}
+
+// templatePattern matches the {name} templates of OpenAPI paths and server URLs
+var templatePattern = regexp.MustCompile(`\{([^{}/]+)\}`)
+
+// pointEscaper drops the braces an example would need to sit inside an injection point
+var pointEscaper = strings.NewReplacer("{", "", "}", "")
+
+// openApiPoints numbers the injection points of one operation and remembers what each one injects into
+type openApiPoints struct {
+	names []string
+}
+
+// Mark turns an example value into the next injection point with the value as its default
+func (op *openApiPoints) Mark(where string, example string) string {
+	op.names = append(op.names, where)
+	return fmt.Sprintf("{p%d:%s}", len(op.names), pointEscaper.Replace(example))
+}
+
+// String lists the points as p1=query:q, p2=json:/name
+func (op *openApiPoints) String() string {
+	var names []string
+	for ix, name := range op.names {
+		names = append(names, fmt.Sprintf("p%d=%s", ix+1, name))
+	}
+	return strings.Join(names, ", ")
+}
+
+type OpenApiService struct {
+	jsonService *JsonService
+	bodyService *BodyService
+}
+
+var openApiServiceInstance *OpenApiService = nil
+
+// Singleton instance of OpenApiService
+func GetOpenApiService() (*OpenApiService, error) {
+	if openApiServiceInstance == nil {
+		var jsonService *JsonService
+		var bodyService *BodyService
+		var err error
+
+		if jsonService, err = GetJsonService(); err != nil {
+			return nil, err
+		}
+
+		if bodyService, err = GetBodyService(); err != nil {
+			return nil, err
+		}
+
+		openApiServiceInstance = &OpenApiService{
+			jsonService: jsonService,
+			bodyService: bodyService,
+		}
+	}
+
+	return openApiServiceInstance, nil
+}
+
+// Parse reads a JSON or YAML specification, JSON being a subset of YAML
+func (oas *OpenApiService) Parse(content string) (map[string]interface{}, error) {
+	var document interface{}
+	if err := yaml.Unmarshal([]byte(content), &document); err != nil {
+		return nil, err
+	}
+
+	spec := asMap(normalizeYaml(document))
+	if spec == nil {
+		return nil, fmt.Errorf("specification is not a mapping")
+	}
+	return spec, nil
+}
+
+// specKeyPattern matches the version key of a YAML specification at the start of a line
+var specKeyPattern = regexp.MustCompile(`(?m)^["']?(openapi|swagger)["']?\s*:`)
+
+// IsSpec reports whether the head of a document is an OpenAPI 3 or Swagger 2 specification, by its top-level
+// openapi or swagger key. The head is enough, the document isn't parsed.
+func (oas *OpenApiService) IsSpec(head string) bool {
+	if strings.HasPrefix(strings.TrimSpace(head), "{") {
+		for _, key := range oas.jsonService.TopLevelKeys(head) {
+			if key == "openapi" || key == "swagger" {
+				return true
+			}
+		}
+		return false
+	}
+
+	return specKeyPattern.MatchString(head)
+}
+
+// Requests builds a request for every operation of the specification with one of the methods. String path and
+// query parameters and string body fields become named injection points holding their example value, so the
+// attack strategy injects them one by one. Header and cookie parameters are sent with their example value.
+// Only operations responding with HTML can reflect a payload, the others are kept when allData is set because
+// a frontend page renders what they store.
+func (oas *OpenApiService) Requests(content string, server string, methods []string, allData bool) ([]map[string]string, error) {
+	spec, err := oas.Parse(content)
+	if err != nil {
+		return nil, err
+	}
+
+	base, err := oas.BaseUrl(spec, server)
+	if err != nil {
+		return nil, err
+	}
+
+	paths := asMap(spec["paths"])
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("specification without paths")
+	}
+
+	allowed := make(map[string]struct{})
+	for _, method := range methods {
+		allowed[strings.ToLower(strings.TrimSpace(method))] = struct{}{}
+	}
+
+	var requests []map[string]string
+	skippedMethod, skippedData := 0, 0
+	for _, path := range mapKeys(paths) {
+		pathItem := oas.Resolve(spec, paths[path])
+
+		for _, method := range openApiMethods {
+			operation := asMap(pathItem[method])
+			if operation == nil {
+				continue
+			}
+
+			if _, ok := allowed[method]; !ok {
+				skippedMethod++
+				continue
+			}
+			if !allData && !oas.RespondsHtml(spec, operation) {
+				skippedData++
+				continue
+			}
+
+			request, points, err := oas.Request(spec, base, path, method, pathItem, operation)
+			if err != nil {
+				utils.Log.Warn(fmt.Sprintf("Skipping operation %s %s: %s", strings.ToUpper(method), path, err))
+				continue
+			}
+
+			if len(points.names) > 0 {
+				utils.Log.Log(fmt.Sprintf("Operation %s %s: %s", request["_ METHOD"], path, points))
+			}
+			requests = append(requests, request)
+		}
+	}
+
+	utils.Log.Info(fmt.Sprintf("OpenAPI operations imported: [%d], skipped by method: [%d], skipped as not responding with HTML: [%d]", len(requests), skippedMethod, skippedData))
+	if skippedData > 0 {
+		utils.Log.Info("Operations returning data are scanned with -V giving the frontend page that renders it")
+	}
+	return requests, nil
+}
+
+// BaseUrl returns the absolute URL the paths are appended to. The --server URL replaces the scheme and host
+// of the spec's first server and completes a relative one.
+func (oas *OpenApiService) BaseUrl(spec map[string]interface{}, server string) (*url.URL, error) {
+	specUrl := "/"
+
+	if servers, ok := spec["servers"].([]interface{}); ok && len(servers) > 0 {
+		first := asMap(servers[0])
+		specUrl, _ = first["url"].(string)
+
+		// server variables take their default value
+		variables := asMap(first["variables"])
+		specUrl = templatePattern.ReplaceAllStringFunc(specUrl, func(template string) string {
+			variable := asMap(variables[strings.Trim(template, "{}")])
+			return scalarString(variable["default"])
+		})
+	} else if host, ok := spec["host"].(string); ok {
+		// Swagger 2 splits the server into schemes, host and basePath
+		scheme := "https"
+		if schemes, ok := spec["schemes"].([]interface{}); ok && len(schemes) > 0 {
+			scheme = scalarString(schemes[0])
+		}
+		basePath, _ := spec["basePath"].(string)
+		specUrl = scheme + "://" + host + basePath
+	} else if basePath, ok := spec["basePath"].(string); ok {
+		specUrl = basePath
+	}
+
+	base, err := url.Parse(specUrl)
+	if err != nil {
+		return nil, err
+	}
+
+	if server != "" {
+		override, err := url.Parse(server)
+		if err != nil {
+			return nil, err
+		}
+		base.Scheme = override.Scheme
+		base.Host = override.Host
+	}
+
+	if base.Host == "" {
+		return nil, fmt.Errorf("server URL %q is relative, set --server", specUrl)
+	}
+	return base, nil
+}
+
+// Request builds the request of one operation and the injection points it holds
+func (oas *OpenApiService) Request(spec map[string]interface{}, base *url.URL, path string, method string, pathItem map[string]interface{}, operation map[string]interface{}) (map[string]string, *openApiPoints, error) {
+	points := &openApiPoints{}
+	params := oas.Parameters(spec, pathItem, operation)
+
+	request := map[string]string{
+		"_ METHOD":   strings.ToUpper(method),
+		"_ SCHEME":   base.Scheme,
+		"_ PROTOCOL": "HTTP/1.1",
+		"_ BODY":     "",
+		"Host":       base.Host,
+	}
+
+	// path parameters, the ones the spec forgot to declare get a placeholder
+	pathParams := make(map[string]map[string]interface{})
+	for _, param := range params {
+		if param["in"] == "path" {
+			pathParams[scalarString(param["name"])] = param
+		}
+	}
+	filledPath := templatePattern.ReplaceAllStringFunc(path, func(template string) string {
+		name := strings.Trim(template, "{}")
+		param, ok := pathParams[name]
+		if !ok {
+			return "1"
+		}
+
+		example := oas.ParameterExample(spec, param)
+		if value, ok := example.(string); ok {
+			return points.Mark("path:"+name, url.PathEscape(value))
+		}
+		return url.PathEscape(scalarString(example))
+	})
+
+	var query []string
+	var cookies []string
+	var formParams []map[string]interface{}
+	for _, param := range params {
+		name := scalarString(param["name"])
+		example := oas.ParameterExample(spec, param)
+
+		switch param["in"] {
+		case "query":
+			if value, ok := example.(string); ok {
+				query = append(query, url.QueryEscape(name)+"="+points.Mark("query:"+name, url.QueryEscape(value)))
+			} else {
+				query = append(query, url.QueryEscape(name)+"="+url.QueryEscape(scalarString(example)))
+			}
+		case "header":
+			if _, controlled := browserControlledHeaders[strings.ToLower(name)]; !controlled {
+				request[name] = scalarString(example)
+			}
+		case "cookie":
+			cookies = append(cookies, name+"="+scalarString(example))
+		case "formData":
+			formParams = append(formParams, param)
+		case "body":
+			// Swagger 2 body parameter, the consumes list gives its media type
+			mediaType := oas.Consumes(spec, operation, "application/json")
+			if err := oas.SetBody(spec, request, mediaType, asMap(param["schema"]), param["example"], points); err != nil {
+				return nil, nil, err
+			}
+		}
+	}
+
+	fullPath := strings.TrimSuffix(base.EscapedPath(), "/") + filledPath
+	if len(query) > 0 {
+		fullPath += "?" + strings.Join(query, "&")
+	}
+	request["_ PATH"] = fullPath
+
+	if len(cookies) > 0 {
+		request["Cookie"] = strings.Join(cookies, "; ")
+	}
+
+	if len(formParams) > 0 {
+		// Swagger 2 form parameters are the properties of an object body
+		properties := make(map[string]interface{})
+		for _, param := range formParams {
+			schema := make(map[string]interface{}, len(param))
+			for key, value := range param {
+				schema[key] = value
+			}
+			if example, ok := param["x-example"]; ok {
+				schema["example"] = example
+			}
+			properties[scalarString(param["name"])] = schema
+		}
+
+		mediaType := oas.Consumes(spec, operation, "application/x-www-form-urlencoded")
+		schema := map[string]interface{}{"type": "object", "properties": properties}
+		if err := oas.SetBody(spec, request, mediaType, schema, nil, points); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	if requestBody := oas.Resolve(spec, operation["requestBody"]); requestBody != nil {
+		content := asMap(requestBody["content"])
+		mediaType := oas.PickMediaType(mapKeys(content))
+		if mediaType != "" {
+			media := asMap(content[mediaType])
+
+			example := media["example"]
+			if examples := asMap(media["examples"]); example == nil && len(examples) > 0 {
+				example = oas.Resolve(spec, examples[mapKeys(examples)[0]])["value"]
+			}
+
+			if err := oas.SetBody(spec, request, mediaType, asMap(media["schema"]), example, points); err != nil {
+				return nil, nil, err
+			}
+		}
+	}
+
+	return request, points, nil
+}
+
+// Parameters merges the parameters of the path item with the ones of the operation, which win on name and location
+func (oas *OpenApiService) Parameters(spec map[string]interface{}, pathItem map[string]interface{}, operation map[string]interface{}) []map[string]interface{} {
+	var params []map[string]interface{}
+	index := make(map[string]int)
+
+	for _, list := range []interface{}{pathItem["parameters"], operation["parameters"]} {
+		items, _ := list.([]interface{})
+		for _, item := range items {
+			param := oas.Resolve(spec, item)
+			if param == nil {
+				continue
+			}
+
+			key := scalarString(param["in"]) + " " + scalarString(param["name"])
+			if ix, exists := index[key]; exists {
+				params[ix] = param
+				continue
+			}
+			index[key] = len(params)
+			params = append(params, param)
+		}
+	}
+
+	return params
+}
+
+// ParameterExample returns the example of a parameter, from the parameter itself or from its schema
+func (oas *OpenApiService) ParameterExample(spec map[string]interface{}, param map[string]interface{}) interface{} {
+	if example, ok := param["example"]; ok {
+		return example
+	}
+	if example, ok := param["x-example"]; ok {
+		return example
+	}
+	if examples := asMap(param["examples"]); len(examples) > 0 {
+		return oas.Resolve(spec, examples[mapKeys(examples)[0]])["value"]
+	}
+
+	// Swagger 2 non-body parameters carry their schema inline
+	if schema, ok := param["schema"]; ok {
+		return oas.Example(spec, schema, nil)
+	}
+	return oas.Example(spec, param, nil)
+}
+
+// Consumes returns the first media type an operation consumes, Swagger 2 only
+func (oas *OpenApiService) Consumes(spec map[string]interface{}, operation map[string]interface{}, fallback string) string {
+	for _, list := range []interface{}{operation["consumes"], spec["consumes"]} {
+		if items, ok := list.([]interface{}); ok && len(items) > 0 {
+			var mediaTypes []string
+			for _, item := range items {
+				mediaTypes = append(mediaTypes, scalarString(item))
+			}
+			if mediaType := oas.PickMediaType(mediaTypes); mediaType != "" {
+				return mediaType
+			}
+		}
+	}
+	return fallback
+}
+
+// PickMediaType prefers the body formats the body injection understands
+func (oas *OpenApiService) PickMediaType(mediaTypes []string) string {
+	for _, wanted := range []string{"json", "x-www-form-urlencoded", "multipart/form-data", "text/", "xml"} {
+		for _, mediaType := range mediaTypes {
+			if strings.Contains(strings.ToLower(mediaType), wanted) {
+				return mediaType
+			}
+		}
+	}
+	if len(mediaTypes) > 0 {
+		return mediaTypes[0]
+	}
+	return ""
+}
+
+// SetBody encodes the example of a body schema in the media type with its string fields as injection points
+func (oas *OpenApiService) SetBody(spec map[string]interface{}, request map[string]string, mediaType string, schema map[string]interface{}, example interface{}, points *openApiPoints) error {
+	if example == nil {
+		example = oas.Example(spec, schema, nil)
+	}
+	request["Content-Type"] = mediaType
+	lowerType := strings.ToLower(mediaType)
+
+	switch {
+	case strings.Contains(lowerType, "json"):
+		body, err := oas.jsonService.encodeDocument(oas.MarkJson(example, "", points))
+		if err != nil {
+			return err
+		}
+		request["_ BODY"] = body
+
+	case strings.Contains(lowerType, "x-www-form-urlencoded"):
+		fields := asMap(example)
+		var pairs []string
+		for _, name := range mapKeys(fields) {
+			value := url.QueryEscape(scalarString(fields[name]))
+			if _, ok := fields[name].(string); ok {
+				value = points.Mark("body:"+name, value)
+			}
+			pairs = append(pairs, url.QueryEscape(name)+"="+value)
+		}
+		request["_ BODY"] = strings.Join(pairs, "&")
+
+	case strings.Contains(lowerType, "multipart/form-data"):
+		fields := asMap(example)
+		properties := asMap(oas.Resolve(spec, schema)["properties"])
+
+		var parts []formPart
+		for _, name := range mapKeys(fields) {
+			part := formPart{Name: name, Content: []byte(scalarString(fields[name]))}
+
+			property := oas.Resolve(spec, properties[name])
+			if format := scalarString(property["format"]); format == "binary" || format == "base64" || property["type"] == "file" {
+				// file parts are left to the upload mode
+				part.FileName = "test.txt"
+				part.ContentType = "text/plain"
+			} else if value, ok := fields[name].(string); ok {
+				part.Content = []byte(points.Mark("multipart:"+name, value))
+			}
+			parts = append(parts, part)
+		}
+
+		body, contentType, err := oas.bodyService.BuildMultipart(parts)
+		if err != nil {
+			return err
+		}
+		request["_ BODY"] = body
+		request["Content-Type"] = contentType
+
+	default:
+		if value, ok := example.(string); ok {
+			request["_ BODY"] = points.Mark("body", value)
+		} else {
+			request["_ BODY"] = scalarString(example)
+		}
+	}
+
+	return nil
+}
+
+// MarkJson replaces every string of a JSON example with an injection point, object keys in sorted order
+func (oas *OpenApiService) MarkJson(value interface{}, pointer string, points *openApiPoints) interface{} {
+	switch typed := value.(type) {
+	case map[string]interface{}:
+		marked := make(map[string]interface{}, len(typed))
+		for _, key := range mapKeys(typed) {
+			marked[key] = oas.MarkJson(typed[key], pointer+"/"+jsonPointerEscaper.Replace(key), points)
+		}
+		return marked
+	case []interface{}:
+		marked := make([]interface{}, len(typed))
+		for ix, item := range typed {
+			marked[ix] = oas.MarkJson(item, fmt.Sprintf("%s/%d", pointer, ix), points)
+		}
+		return marked
+	case string:
+		return points.Mark("json:"+pointer, typed)
+	}
+	return value
+}
+
+// Example builds an example value for a schema from its example, default or enum, or a placeholder of its type.
+// refs holds the references being expanded, a recursive schema stops at its first repetition.
+func (oas *OpenApiService) Example(spec map[string]interface{}, node interface{}, refs []string) interface{} {
+	if ref, ok := asMap(node)["$ref"].(string); ok {
+		for _, expanding := range refs {
+			if expanding == ref {
+				return nil
+			}
+		}
+		refs = append(refs, ref)
+	}
+
+	schema := oas.Resolve(spec, node)
+	if schema == nil {
+		return nil
+	}
+
+	for _, key := range []string{"example", "default", "x-example"} {
+		if example, ok := schema[key]; ok {
+			return example
+		}
+	}
+	for _, key := range []string{"examples", "enum"} {
+		if list, ok := schema[key].([]interface{}); ok && len(list) > 0 {
+			return list[0]
+		}
+	}
+
+	if allOf, ok := schema["allOf"].([]interface{}); ok {
+		merged := make(map[string]interface{})
+		for _, part := range allOf {
+			for key, value := range asMap(oas.Example(spec, part, refs)) {
+				merged[key] = value
+			}
+		}
+		return merged
+	}
+	for _, key := range []string{"oneOf", "anyOf"} {
+		if list, ok := schema[key].([]interface{}); ok && len(list) > 0 {
+			return oas.Example(spec, list[0], refs)
+		}
+	}
+
+	schemaType := scalarString(schema["type"])
+	if types, ok := schema["type"].([]interface{}); ok {
+		// OpenAPI 3.1 type lists, null is the least useful choice
+		for _, item := range types {
+			if schemaType = scalarString(item); schemaType != "null" {
+				break
+			}
+		}
+	}
+	if schemaType == "" && schema["properties"] != nil {
+		schemaType = "object"
+	}
+
+	switch schemaType {
+	case "object":
+		object := make(map[string]interface{})
+		properties := asMap(schema["properties"])
+		for _, name := range mapKeys(properties) {
+			if oas.Resolve(spec, properties[name])["readOnly"] == true {
+				continue
+			}
+			if example := oas.Example(spec, properties[name], refs); example != nil {
+				object[name] = example
+			}
+		}
+		return object
+	case "array":
+		if example := oas.Example(spec, schema["items"], refs); example != nil {
+			return []interface{}{example}
+		}
+		return []interface{}{}
+	case "integer":
+		if minimum, ok := schema["minimum"]; ok {
+			return minimum
+		}
+		return 1
+	case "number":
+		if minimum, ok := schema["minimum"]; ok {
+			return minimum
+		}
+		return 1.5
+	case "boolean":
+		return true
+	case "string", "file":
+		if example, ok := schemaFormatExamples[scalarString(schema["format"])]; ok {
+			return example
+		}
+		return "test"
+	}
+
+	return nil
+}
+
+// Resolve follows local $ref pointers like #/components/schemas/Pet and returns the node as a map
+func (oas *OpenApiService) Resolve(spec map[string]interface{}, node interface{}) map[string]interface{} {
+	current := asMap(node)
+
+	for hops := 0; current != nil && hops < maxRefHops; hops++ {
+		ref, ok := current["$ref"].(string)
+		if !ok {
+			return current
+		}
+		if !strings.HasPrefix(ref, "#/") {
+			utils.Log.Warn(fmt.Sprintf("External reference %s is not supported", ref))
+			return nil
+		}
+
+		var target interface{} = spec
+		for _, token := range strings.Split(strings.TrimPrefix(ref, "#/"), "/") {
+			token, _ = url.PathUnescape(token)
+			target = asMap(target)[jsonPointerUnescaper.Replace(token)]
+		}
+		current = asMap(target)
+	}
+
+	return current
+}
+
+// RespondsHtml reports whether a success response of the operation declares an HTML body
+func (oas *OpenApiService) RespondsHtml(spec map[string]interface{}, operation map[string]interface{}) bool {
+	responses := asMap(operation["responses"])
+	for code, response := range responses {
+		if !strings.HasPrefix(code, "2") && code != "default" {
+			continue
+		}
+
+		for mediaType := range asMap(oas.Resolve(spec, response)["content"]) {
+			if strings.Contains(strings.ToLower(mediaType), "html") {
+				return true
+			}
+		}
+	}
+
+	if produces, ok := operation["produces"].([]interface{}); ok {
+		for _, mediaType := range produces {
+			if strings.Contains(strings.ToLower(scalarString(mediaType)), "html") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// normalizeYaml converts what YAML decodes beyond JSON: mappings with non-string keys like status codes
+// and unquoted dates, which examples mean as strings
+func normalizeYaml(node interface{}) interface{} {
+	switch typed := node.(type) {
+	case map[string]interface{}:
+		for key, value := range typed {
+			typed[key] = normalizeYaml(value)
+		}
+		return typed
+	case map[interface{}]interface{}:
+		converted := make(map[string]interface{}, len(typed))
+		for key, value := range typed {
+			converted[fmt.Sprint(key)] = normalizeYaml(value)
+		}
+		return converted
+	case []interface{}:
+		for ix, value := range typed {
+			typed[ix] = normalizeYaml(value)
+		}
+		return typed
+	case time.Time:
+		if typed.Equal(typed.Truncate(24 * time.Hour)) {
+			return typed.Format("2006-01-02")
+		}
+		return typed.Format(time.RFC3339)
+	}
+	return node
+}
+
+// asMap returns a node as a mapping, nil when it is something else
+func asMap(node interface{}) map[string]interface{} {
+	mapping, _ := node.(map[string]interface{})
+	return mapping
+}
+
+// mapKeys returns the keys of a mapping in sorted order
+func mapKeys(mapping map[string]interface{}) []string {
+	keys := make([]string, 0, len(mapping))
+	for key := range mapping {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// scalarString formats an example value for a path, query, header or form field, objects and arrays as JSON
+func scalarString(value interface{}) string {
+	switch typed := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return typed
+	case map[string]interface{}, []interface{}:
+		if encoded, err := json.Marshal(typed); err == nil {
+			return string(encoded)
+		}
+	}
+	return fmt.Sprint(value)
+}
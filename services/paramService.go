@@ -0,0 +1,245 @@
+package services
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+	"xss/utils"
+
+	urlutil "github.com/projectdiscovery/utils/url"
+)
+
+// defaultParams are tried when no wordlist is given
+var defaultParams = []string{
+	"q", "s", "search", "query", "keyword", "term", "id", "name", "page", "type", "view", "category",
+	"lang", "ref", "url", "redirect", "next", "return", "returnUrl", "callback", "message", "msg",
+	"error", "email", "user", "username", "title", "text", "content", "comment", "debug", "test",
+}
+
+var (
+	fieldNamePattern   = regexp.MustCompile(`(?i)<(?:input|textarea|select|button)[^>]*\sname\s*=\s*["']?([^"'\s>]+)`)
+	searchParamPattern = regexp.MustCompile(`\.(?:get|getAll|has)\(\s*["']([\w.\-\[\]]+)["']`)
+	queryStringPattern = regexp.MustCompile(`[?&]([\w\-]+)=`)
+	variablePattern    = regexp.MustCompile(`(?:var|let|const)\s+([A-Za-z_$][\w$]{1,30})\s*=`)
+	scriptSrcPattern   = regexp.MustCompile(`(?i)<script[^>]*\ssrc\s*=\s*["']([^"']+)["']`)
+)
+
+const (
+	// maxParamsPerRequest and maxQueryLength keep the batched probe URLs within what servers accept
+	maxParamsPerRequest = 40
+	maxQueryLength      = 1800
+	// maxScriptsPerPage caps how many same-host scripts are fetched for parameter names
+	maxScriptsPerPage = 10
+)
+
+type ParamService struct {
+	argService     *ArgsService
+	fileService    *FileService
+	requestService *RequestService
+}
+
+var paramServiceInstance *ParamService = nil
+
+// Singleton instance of ParamService
+func GetParamService() (*ParamService, error) {
+	if paramServiceInstance == nil {
+		var argService *ArgsService
+		var fileService *FileService
+		var requestService *RequestService
+		var err error
+
+		if argService, err = GetArgsService(); err != nil {
+			return nil, err
+		}
+
+		if fileService, err = GetFileService(); err != nil {
+			return nil, err
+		}
+
+		if requestService, err = GetRequestService(); err != nil {
+			return nil, err
+		}
+
+		paramServiceInstance = &ParamService{
+			argService:     argService,
+			fileService:    fileService,
+			requestService: requestService,
+		}
+	}
+
+	return paramServiceInstance, nil
+}
+
+// Discover finds unlisted parameters that each URL reflects, keyed by URL. The URLs are read twice, once for
+// the names every host uses and once to probe them. Every request for a URL goes out with the headers the
+// scan sends it with, so authenticated pages are probed logged in.
+func (ps *ParamService) Discover(urls UrlStream, uuid string, headers func(string) map[string]string) (map[string][]string, error) {
+	utils.Log.Info("Running ParamService")
+
+	wordlist, err := ps.GetWordlist()
+	if err != nil {
+		return nil, err
+	}
+
+	// names seen on one page are good guesses for every other page of the same host
+	hostParams := make(map[string][]string)
+	known := make(map[string]struct{})
+	err = urls(func(rawUrl string) error {
+		if parsedUrl, err := urlutil.Parse(rawUrl); err == nil {
+			parsedUrl.Query().Iterate(func(key string, value []string) bool {
+				if _, exists := known[parsedUrl.Host+"?"+key]; !exists {
+					known[parsedUrl.Host+"?"+key] = struct{}{}
+					hostParams[parsedUrl.Host] = append(hostParams[parsedUrl.Host], key)
+				}
+				return true
+			})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	discovered := make(map[string][]string)
+	err = urls(func(rawUrl string) error {
+		if HasInjectionPoints(rawUrl) {
+			return nil
+		}
+
+		parsedUrl, err := urlutil.Parse(rawUrl)
+		if err != nil {
+			return nil
+		}
+
+		candidates := append([]string{}, wordlist...)
+		candidates = append(candidates, hostParams[parsedUrl.Host]...)
+		requestHeaders := headers(rawUrl)
+		candidates = append(candidates, ps.GetPageParams(rawUrl, requestHeaders)...)
+
+		// parameters the URL already has are injected anyway
+		var fresh []string
+		for _, name := range utils.RemoveDuplicates(candidates) {
+			if !parsedUrl.Query().Has(name) {
+				fresh = append(fresh, name)
+			}
+		}
+
+		reflected := ps.Probe(rawUrl, fresh, uuid, requestHeaders)
+		if len(reflected) > 0 {
+			utils.Log.Success(fmt.Sprintf("Reflected parameters on %s: %s", rawUrl, strings.Join(reflected, ", ")))
+			discovered[rawUrl] = reflected
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	utils.Log.Info(fmt.Sprintf("Parameter discovery done, URLs with new parameters: [%d]", len(discovered)))
+	return discovered, nil
+}
+
+// GetWordlist reads the parameter wordlist, or falls back to the built in list
+func (ps *ParamService) GetWordlist() ([]string, error) {
+	wordlistFile, err := ps.argService.Get(ArgKeys.Params)
+	if err != nil || wordlistFile.(string) == "" {
+		return defaultParams, nil
+	}
+
+	fileContent, err := ps.fileService.ReadFileAsString(wordlistFile.(string))
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	for _, line := range strings.Split(fileContent, "\n") {
+		if name := strings.TrimSpace(line); name != "" && !strings.HasPrefix(name, "#") {
+			names = append(names, name)
+		}
+	}
+
+	return names, nil
+}
+
+// GetPageParams collects names from the page's form fields and from parameter and variable usage in its scripts
+func (ps *ParamService) GetPageParams(rawUrl string, headers map[string]string) []string {
+	body, _, err := ps.requestService.Get(rawUrl, headers)
+	if err != nil {
+		return nil
+	}
+
+	var names []string
+	for _, match := range fieldNamePattern.FindAllStringSubmatch(body, -1) {
+		names = append(names, match[1])
+	}
+
+	scripts := []string{body}
+	base, _ := url.Parse(rawUrl)
+	for ix, match := range scriptSrcPattern.FindAllStringSubmatch(body, -1) {
+		if ix >= maxScriptsPerPage || base == nil {
+			break
+		}
+
+		scriptUrl, err := base.Parse(match[1])
+		if err != nil || scriptUrl.Host != base.Host {
+			continue
+		}
+
+		if script, _, err := ps.requestService.Get(scriptUrl.String(), headers); err == nil {
+			scripts = append(scripts, script)
+		}
+	}
+
+	for _, script := range scripts {
+		// .get("name") only means a query parameter when the script reads the query string at all
+		if strings.Contains(script, "URLSearchParams") || strings.Contains(script, "searchParams") {
+			for _, match := range searchParamPattern.FindAllStringSubmatch(script, -1) {
+				names = append(names, match[1])
+			}
+		}
+		for _, match := range queryStringPattern.FindAllStringSubmatch(script, -1) {
+			names = append(names, match[1])
+		}
+		for _, match := range variablePattern.FindAllStringSubmatch(script, -1) {
+			names = append(names, match[1])
+		}
+	}
+
+	return names
+}
+
+// Probe sends the candidates in batches, each with its own value, and returns the ones whose value comes back
+func (ps *ParamService) Probe(rawUrl string, candidates []string, uuid string, headers map[string]string) []string {
+	var reflected []string
+
+	for start := 0; start < len(candidates); {
+		parsedUrl, err := urlutil.Parse(rawUrl)
+		if err != nil {
+			return reflected
+		}
+
+		values := make(map[string]string)
+		end := start
+		for end < len(candidates) && end-start < maxParamsPerRequest && len(parsedUrl.Query().Encode()) < maxQueryLength {
+			value := fmt.Sprintf("xp%d%s", end, uuid[len(uuid)-6:])
+			parsedUrl.Query().Set(candidates[end], value)
+			values[candidates[end]] = value
+			end++
+		}
+		start = end
+
+		body, _, err := ps.requestService.Get(parsedUrl.String(), headers)
+		if err != nil {
+			continue
+		}
+
+		for name, value := range values {
+			if strings.Contains(body, value) {
+				reflected = append(reflected, name)
+			}
+		}
+	}
+
+	return reflected
+}
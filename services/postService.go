@@ -3,7 +3,7 @@ package services
 import (
 	"context"
 	"fmt"
-	"net/url"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
@@ -15,15 +15,24 @@ import (
 
 type PostService struct {
 	CombinedUrls []map[string]string
-	Request      map[string]string
+	Requests     []map[string]string
 	Payloads     []string
+	RawPayloads  []string
 	UUID         string
 
-	fileService    *FileService
-	argService     *ArgsService
-	jsonService    *JsonService
-	urlService     *UrlService
-	requestService *RequestService
+	fileService     *FileService
+	argService      *ArgsService
+	jsonService     *JsonService
+	urlService      *UrlService
+	requestService  *RequestService
+	reportService   *ReportService
+	storedService   *StoredService
+	strategy        *StrategyService
+	bodyService     *BodyService
+	uploadService   *UploadService
+	importService   *ImportService
+	scopeService    *ScopeService
+	resourceService *ResourceService
 }
 
 var postServiceInstance *PostService = nil
@@ -36,6 +45,14 @@ func GetPostService() (*PostService, error) {
 		var jsonService *JsonService
 		var urlService *UrlService
 		var requestService *RequestService
+		var reportService *ReportService
+		var storedService *StoredService
+		var strategy *StrategyService
+		var bodyService *BodyService
+		var uploadService *UploadService
+		var importService *ImportService
+		var scopeService *ScopeService
+		var resourceService *ResourceService
 		var err error
 
 		if fileService, err = GetFileService(); err != nil {
@@ -58,12 +75,52 @@ func GetPostService() (*PostService, error) {
 			return nil, err
 		}
 
+		if reportService, err = GetReportService(); err != nil {
+			return nil, err
+		}
+
+		if storedService, err = GetStoredService(); err != nil {
+			return nil, err
+		}
+
+		if strategy, err = GetStrategyService(); err != nil {
+			return nil, err
+		}
+
+		if bodyService, err = GetBodyService(); err != nil {
+			return nil, err
+		}
+
+		if uploadService, err = GetUploadService(); err != nil {
+			return nil, err
+		}
+
+		if importService, err = GetImportService(); err != nil {
+			return nil, err
+		}
+
+		if scopeService, err = GetScopeService(); err != nil {
+			return nil, err
+		}
+
+		if resourceService, err = GetResourceService(); err != nil {
+			return nil, err
+		}
+
 		postServiceInstance = &PostService{
-			fileService:    fileService,
-			argService:     argService,
-			jsonService:    jsonService,
-			urlService:     urlService,
-			requestService: requestService,
+			fileService:     fileService,
+			argService:      argService,
+			jsonService:     jsonService,
+			urlService:      urlService,
+			requestService:  requestService,
+			reportService:   reportService,
+			storedService:   storedService,
+			strategy:        strategy,
+			bodyService:     bodyService,
+			uploadService:   uploadService,
+			importService:   importService,
+			scopeService:    scopeService,
+			resourceService: resourceService,
 		}
 	}
 
@@ -79,10 +136,12 @@ func (ps *PostService) Run() {
 		utils.Log.Info("UUID generated", ps.UUID)
 	}
 
-	if err := ps.GetRequest(); err != nil {
+	if err := ps.GetRequests(); err != nil {
 		utils.HandleErr(err)
 	} else {
-		utils.Log.Info("Request parsed [", ps.Request["_ METHOD"], ps.Request["_ PATH"], "]")
+		for _, request := range ps.Requests {
+			utils.Log.Info("Request parsed [", request["_ METHOD"], request["_ PATH"], "]")
+		}
 	}
 
 	if err := ps.GetPayloads(); err != nil {
@@ -91,13 +150,19 @@ func (ps *PostService) Run() {
 		utils.Log.Info(fmt.Sprintf("Payloads parsed: [%d]", len(ps.Payloads)))
 	}
 
+	points, err := ps.GetPointPayloads()
+	if err != nil {
+		utils.HandleErr(err)
+	}
+
+	// every request goes into one queue, sharing the browser, the threads and the report
 	var combinedRequests []map[string]string
-	for _, payload := range ps.Payloads {
-		newRequest, err := ps.urlService.CombineRequestWithPayload(utils.CloneMap(ps.Request), payload)
-		if err != nil {
-			utils.HandleErr(err)
-		}
-		combinedRequests = append(combinedRequests, newRequest)
+	for _, request := range ps.Requests {
+		combinedRequests = append(combinedRequests, ps.CombineRequest(request, points)...)
+	}
+
+	if len(combinedRequests) == 0 {
+		utils.HandleErr(fmt.Errorf("no injection points found in the requests"))
 	}
 
 	utils.Log.Info(fmt.Sprintf("Generated [%d] requests", len(combinedRequests)))
@@ -106,25 +171,113 @@ func (ps *PostService) Run() {
 	time.Sleep(2 * time.Second)
 }
 
-func (ps *PostService) GetRequest() error {
-	var request map[string]string
+// CombineRequest generates every injected copy of a single request with the enabled injection modes
+func (ps *PostService) CombineRequest(request map[string]string, points map[string][]string) []map[string]string {
+	var combinedRequests []map[string]string
 
-	if requestFile, err := ps.argService.Get(ArgKeys.Urls); err != nil {
+	if ps.uploadService.Enabled() {
+		combinedRequests = ps.uploadService.CombineUploadWithPayload(request, ps.UUID)
+	} else if names := ps.strategy.Points(ps.RequestValues(request)...); len(names) > 0 {
+		strategy := ps.argService.GetAll()[ArgKeys.Strategy].(string)
+		for _, attack := range ps.strategy.Combine(names, ps.RawPayloads, points) {
+			// the body keeps its format valid, the path and headers take the payloads escaped like in the query
+			escaped := ps.strategy.Encode(attack, ps.urlService.EscapePayload)
+			encoded := ps.strategy.Encode(attack, func(payload string) string {
+				return ps.bodyService.EncodeValue(request, payload)
+			})
+
+			newRequest := utils.CloneMap(request)
+			for key, value := range newRequest {
+				if key == "_ BODY" {
+					newRequest[key] = ps.strategy.Fill(value, encoded)
+				} else {
+					newRequest[key] = ps.strategy.Fill(value, escaped)
+				}
+			}
+			newRequest["_ LOCATION"] = "marker"
+			newRequest["_ PARAM"] = strings.Join(attack.Targets, ",")
+			newRequest["_ VARIANT"] = strategy
+			combinedRequests = append(combinedRequests, newRequest)
+		}
+	} else if !strings.Contains(request["_ BODY"], "{payload}") {
+		// without a marker every field of a known body format and every query parameter of the path is
+		// injected on its own
+		combinedRequests = ps.bodyService.CombineBodyWithPayload(request, ps.RawPayloads)
+		combinedRequests = append(combinedRequests, ps.urlService.CombineRequestQueryWithPayload(request, ps.RawPayloads)...)
+	}
+
+	if len(combinedRequests) == 0 {
+		for _, payload := range ps.Payloads {
+			newRequest, err := ps.urlService.CombineRequestWithPayload(utils.CloneMap(request), payload)
+			if err != nil {
+				utils.Log.Warn(fmt.Sprintf("Skipping %s %s: %s", request["_ METHOD"], request["_ PATH"], err))
+				return nil
+			}
+			combinedRequests = append(combinedRequests, newRequest)
+		}
+	}
+
+	return combinedRequests
+}
+
+// GetRequests parses the request file, every file of a directory or glob, or several requests in one file
+func (ps *PostService) GetRequests() error {
+	if ps.importService.Imported() {
+		ps.Requests = ps.InScope(ps.importService.Requests)
+		return nil
+	}
+
+	sources, err := ps.argService.Get(ArgKeys.Urls)
+	if err != nil {
 		return err
-	} else {
-		if fileContent, err := ps.fileService.ReadFileAsString(requestFile.(string)); err != nil {
+	}
+
+	var files []string
+	for _, source := range sources.([]string) {
+		if ps.urlService.IsLiteralUrl(source) {
+			return fmt.Errorf("%s: %s requests are read from request files, a URL is only a target for GET", source, ps.argService.GetAll()[ArgKeys.Method])
+		}
+
+		expanded, err := ps.fileService.ExpandPath(source)
+		if err != nil {
 			return err
-		} else {
-			if request, err = ps.urlService.ParseRequest(fileContent); err != nil {
-				return err
-			}
 		}
+		files = append(files, expanded...)
 	}
 
-	ps.Request = request
+	var requests []map[string]string
+	for _, file := range files {
+		fileContent, err := ps.fileService.ReadFileAsString(file)
+		if err != nil {
+			return err
+		}
+
+		parsed, err := ps.urlService.ParseRequests(fileContent)
+		if err != nil {
+			return fmt.Errorf("%s: %w", file, err)
+		}
+		requests = append(requests, parsed...)
+	}
+
+	ps.Requests = ps.InScope(requests)
 	return nil
 }
 
+// InScope drops the requests whose target the scope rules leave out
+func (ps *PostService) InScope(requests []map[string]string) []map[string]string {
+	var kept []map[string]string
+	for _, request := range requests {
+		if ps.scopeService.InScope(ps.GetTargetUrl(request)) {
+			kept = append(kept, request)
+		}
+	}
+
+	if dropped := len(requests) - len(kept); dropped > 0 {
+		utils.Log.Info(fmt.Sprintf("Requests out of scope: [%d]", dropped))
+	}
+	return kept
+}
+
 func (ps *PostService) GetPayloads() error {
 	var payloads []string
 
@@ -139,23 +292,56 @@ func (ps *PostService) GetPayloads() error {
 	}
 
 	var validPayloads []string
+	var rawPayloads []string
 	for _, payload := range payloads {
 		if strings.Trim(payload, " ") == "" {
 			continue
 		}
 
 		formattedPayload := strings.Replace(payload, "###", ps.UUID, -1)
-		if _, err := url.ParseQuery(fmt.Sprintf("?id=%s", formattedPayload)); err != nil {
-			validPayloads = append(validPayloads, url.QueryEscape(formattedPayload))
-		} else {
-			validPayloads = append(validPayloads, formattedPayload)
-		}
+		rawPayloads = append(rawPayloads, formattedPayload)
+		validPayloads = append(validPayloads, ps.urlService.EscapePayload(formattedPayload))
 	}
 
 	ps.Payloads = validPayloads
+	ps.RawPayloads = rawPayloads
 	return nil
 }
 
+// GetPointPayloads reads the payload files of the named injection points, they are escaped per location when filled
+func (ps *PostService) GetPointPayloads() (map[string][]string, error) {
+	return ps.strategy.GetPointPayloads(ps.UUID)
+}
+
+// RequestValues returns the request path, body and header values in a stable order
+func (ps *PostService) RequestValues(request map[string]string) []string {
+	keys := make([]string, 0, len(request))
+	for key := range request {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var values []string
+	for _, key := range keys {
+		values = append(values, request[key])
+	}
+	return values
+}
+
+// GetTargetUrl builds the absolute URL the request is sent to
+func (ps *PostService) GetTargetUrl(request map[string]string) string {
+	options := ps.argService.GetAll()
+
+	// an absolute-form target or HTTP/2 :scheme in the request file wins over --protocol
+	protocol := options[ArgKeys.Protocol].(string)
+	if request["_ SCHEME"] != "" {
+		protocol = request["_ SCHEME"]
+	}
+
+	_, host := HeaderValue(request, "Host")
+	return fmt.Sprintf("%s://%s%s", protocol, host, request["_ PATH"])
+}
+
 func (ps *PostService) GetUUID() error {
 	dateNow := strconv.FormatInt(time.Now().UnixMicro(), 10)
 	ps.UUID = strings.Replace(dateNow, ".", "", -1)
@@ -246,11 +432,12 @@ func (ps *PostService) Scan(ctx playwright.BrowserContext) error {
 			break
 		}
 		go func(url map[string]string, pageIndex int) {
-			if found, err := ps.Send(ctx, url, pageIndex); err != nil {
+			if found, offScope, err := ps.Send(ctx, url, pageIndex); err != nil {
 				utils.Log.Error(fmt.Sprintf("Error sending request: %s", err))
 			} else if found {
 				m.Lock()
 				foundXss = append(foundXss, url)
+				ps.reportService.AddFinding(Finding{Target: url["_ BODY"], Location: url["_ LOCATION"], Param: url["_ PARAM"], Variant: url["_ VARIANT"], OffScope: offScope})
 				utils.Log.Success(fmt.Sprintf("Found total %d urls", len(foundXss)))
 				m.Unlock()
 			}
@@ -283,22 +470,8 @@ func (ps *PostService) Scan(ctx playwright.BrowserContext) error {
 		}
 
 		if options[ArgKeys.Report].(string) != "" {
-			foundXssBodies := func(s []map[string]string) []string {
-				var foundXss []string
-				for _, url := range s {
-					foundXss = append(foundXss, url["_ BODY"])
-				}
-				return foundXss
-			}(foundXss)
-
-			if foundXssJson, err := ps.jsonService.ArrayToString(foundXssBodies); err != nil {
+			if err := ps.reportService.SaveReport(options[ArgKeys.Report].(string)); err != nil {
 				utils.HandleErr(err)
-			} else {
-				if err := ps.fileService.WriteFileAsString(options[ArgKeys.Report].(string), foundXssJson); err != nil {
-					utils.HandleErr(err)
-				} else {
-					utils.Log.Info(fmt.Sprintf("Report saved to: %s", options[ArgKeys.Report].(string)))
-				}
 			}
 		}
 	}()
@@ -312,18 +485,31 @@ func (ps *PostService) Scan(ctx playwright.BrowserContext) error {
 	return nil
 }
 
-func (ps *PostService) Send(ctx playwright.BrowserContext, url map[string]string, pageIndex int) (foundXss bool, _ error) {
+// Send loads the request in a new page and waits for its dialog, offScope is the page the dialog fired on when it
+// is out of scope
+func (ps *PostService) Send(ctx playwright.BrowserContext, url map[string]string, pageIndex int) (foundXss bool, offScope string, _ error) {
 	options := ps.argService.GetAll()
 
 	var page playwright.Page
 	var err error
 
+	marker := ps.UUID
+	if ps.storedService.Enabled() {
+		// every injection gets its own canary so an execution on a verification page can be traced back to it
+		if marker, err = ps.storedService.Inject(url["_ BODY"], url["_ LOCATION"], url["_ PARAM"]); err != nil {
+			return false, "", err
+		}
+		url = utils.ReplaceInMap(url, ps.UUID, marker)
+		defer ps.storedService.Verify()
+	}
+
 	page, err = ctx.NewPage()
 	if err != nil {
-		return false, err
+		return false, "", err
 	}
+	ps.resourceService.Track(page)
 
-	fullUrl := fmt.Sprintf("%s://%s%s", options[ArgKeys.Protocol], url["Host"], url["_ PATH"])
+	fullUrl := ps.GetTargetUrl(url)
 
 	dialogChan := make(chan bool, 1)
 	pageLoadChan := make(chan bool, 1)
@@ -334,11 +520,15 @@ func (ps *PostService) Send(ctx playwright.BrowserContext, url map[string]string
 		if err := dialog.Accept(); err != nil {
 			utils.Log.Error(fmt.Sprintf("Error accepting dialog %s: %s, %s", dialogType, dialogMsg, url["_ BODY"]))
 		} else if !foundXss {
-			if dialogMsg == ps.UUID {
+			if dialogMsg == marker {
 				utils.Log.Success(fmt.Sprintf("XSS found: %s", url["_ BODY"]))
 			} else {
 				utils.Log.Warn(fmt.Sprintf("Alert found with UUID missmatch: %s %s", dialogMsg, url["_ BODY"]))
 			}
+			if landed := page.URL(); ps.scopeService.Block(landed) != "" {
+				utils.Log.Warn(fmt.Sprintf("XSS fired after leaving the scope, on: %s", landed))
+				offScope = landed
+			}
 			foundXss = true
 			dialogChan <- true
 		}
@@ -359,22 +549,30 @@ func (ps *PostService) Send(ctx playwright.BrowserContext, url map[string]string
 	page.On("dialog", handleDialog)
 	page.On("load", handlePageLoad)
 
+	cookies, err := ctx.Cookies(fullUrl)
+	if err != nil {
+		return false, "", err
+	}
+
 	err = page.Route(fullUrl, func(route playwright.Route) {
-		// send post request
-		response, respHeaders, err := ps.requestService.Post(fullUrl, url["_ BODY"], url)
+		// the request goes out through the context, with the cookies and session of the identity injecting
+		response, err := route.Fetch(playwright.RouteFetchOptions{
+			Method:   playwright.String(url["_ METHOD"]),
+			PostData: url["_ BODY"],
+			Headers:  ps.requestService.FetchHeaders(url, cookies),
+			Timeout:  playwright.Float(float64(options[ArgKeys.Timeout].(int))),
+		})
 		if err != nil {
 			utils.Log.Error(fmt.Sprintf("Error sending request: %s", err))
+			route.Abort()
 			return
 		}
 
-		route.Fulfill(playwright.RouteFulfillOptions{
-			Body:    response,
-			Headers: respHeaders,
-		})
+		route.Fulfill(playwright.RouteFulfillOptions{Response: response})
 	})
 
 	if err != nil {
-		return false, err
+		return false, "", err
 	}
 
 	defer func() {
@@ -391,25 +589,26 @@ func (ps *PostService) Send(ctx playwright.BrowserContext, url map[string]string
 		}
 	}()
 
+	loadStart := time.Now()
 	if _, err := page.Goto(fullUrl); err != nil {
-		return false, err
+		return false, "", err
 	}
 
 	select {
 	case <-pageLoadChan:
-		// Page loaded
+		ps.resourceService.Loaded(page, time.Since(loadStart))
 	case <-time.After(time.Duration(options[ArgKeys.Timeout].(int)) * time.Millisecond):
 		utils.Log.Warn(fmt.Sprintf("Timeout loading page: %s", url))
-		return false, nil
+		return false, "", nil
 	}
 
 	select {
 	case <-dialogChan:
 		page.Close()
 		page = nil
-		return true, nil
+		return true, offScope, nil
 	case <-time.After(time.Duration(1000) * time.Millisecond):
 	}
 
-	return false, nil
+	return false, "", nil
 }
@@ -1,10 +1,70 @@
 package services
 
-import "xss/utils"
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"xss/utils"
+)
+
+// Finding describes a single confirmed execution and where the payload was injected
+type Finding struct {
+	Target   string
+	Location string
+	Param    string
+	Variant  string
+	Trigger  string
+	Page     string
+	Canary   string
+	OffScope string
+
+	InjectedAs  string
+	TriggeredAs string
+}
+
+// String formats the finding as a single report line, plain target when there is nothing to attribute
+func (f Finding) String() string {
+	var parts []string
+	if f.Location != "" {
+		parts = append(parts, "location: "+f.Location)
+	}
+	if f.Param != "" {
+		parts = append(parts, "param: "+f.Param)
+	}
+	if f.Variant != "" {
+		parts = append(parts, "variant: "+f.Variant)
+	}
+	if f.Trigger != "" {
+		parts = append(parts, "trigger: "+f.Trigger)
+	}
+	if f.Page != "" {
+		parts = append(parts, "fired on: "+f.Page)
+	}
+	if f.Canary != "" {
+		parts = append(parts, "canary: "+f.Canary)
+	}
+	if f.OffScope != "" {
+		parts = append(parts, "fired off scope: "+f.OffScope)
+	}
+	if f.InjectedAs != "" {
+		parts = append(parts, "injected as: "+f.InjectedAs)
+	}
+	if f.TriggeredAs != "" {
+		parts = append(parts, "triggered as: "+f.TriggeredAs)
+	}
+
+	if len(parts) == 0 {
+		return f.Target
+	}
+	return fmt.Sprintf("%s [%s]", f.Target, strings.Join(parts, ", "))
+}
 
 type ReportService struct {
 	jsonService *JsonService
 	fileService *FileService
+
+	findings []Finding
+	m        sync.Mutex
 }
 
 var reportServiceInstance *ReportService
@@ -30,6 +90,42 @@ func GetReportService() (*ReportService, error) {
 	return reportServiceInstance, nil
 }
 
+// AddFinding records a finding so it ends up in the report, safe to call from scan goroutines
+func (rs *ReportService) AddFinding(finding Finding) {
+	rs.m.Lock()
+	defer rs.m.Unlock()
+
+	rs.findings = append(rs.findings, finding)
+}
+
+// GetFindings returns a copy of all findings recorded so far
+func (rs *ReportService) GetFindings() []Finding {
+	rs.m.Lock()
+	defer rs.m.Unlock()
+
+	return append([]Finding{}, rs.findings...)
+}
+
+// SaveReport writes every finding recorded so far to the report file
+func (rs *ReportService) SaveReport(reportFile string) error {
+	var report []string
+	for _, finding := range rs.GetFindings() {
+		report = append(report, finding.String())
+	}
+
+	reportJson, err := rs.jsonService.ArrayToString(report)
+	if err != nil {
+		return err
+	}
+
+	if err := rs.fileService.WriteFileAsString(reportFile, reportJson); err != nil {
+		return err
+	}
+
+	utils.Log.Info(fmt.Sprintf("Report saved to: %s", reportFile))
+	return nil
+}
+
 // DisplayReport reads the report file and displays the report
 func (rs *ReportService) DisplayReport(reportFile string) error {
 	var report []string
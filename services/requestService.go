@@ -8,8 +8,18 @@ import (
 	"net/http"
 	"strings"
 	"time"
+
+	"github.com/playwright-community/playwright-go"
 )
 
+// browserSetHeaders are set by the browser for the request it sends, the request file values no longer fit
+var browserSetHeaders = map[string]bool{
+	"host":            true,
+	"content-length":  true,
+	"connection":      true,
+	"accept-encoding": true,
+}
+
 type RequestService struct{}
 
 var requestServerInstance *RequestService = nil
@@ -23,6 +33,17 @@ func GetRequestService() (*RequestService, error) {
 	return requestServerInstance, nil
 }
 
+// Get sends a plain GET request, headers use the same format as Post
+func (rs *RequestService) Get(url string, headers map[string]string) (string, map[string]string, error) {
+	request := make(map[string]string, len(headers)+1)
+	for key, value := range headers {
+		request[key] = value
+	}
+	request["_ METHOD"] = "GET"
+
+	return rs.Post(url, "", request)
+}
+
 func (rs *RequestService) Post(url string, payload string, headers map[string]string) (string, map[string]string, error) {
 	// Convert payload to JSON
 	// jsonData, err := json.Marshal(payload)
@@ -45,10 +66,13 @@ func (rs *RequestService) Post(url string, payload string, headers map[string]st
 		if strings.HasPrefix(key, "_ ") {
 			continue
 		}
-		req.Header.Set(key, value)
+		// repeated headers of a request file are joined by newlines
+		for _, line := range strings.Split(value, "\n") {
+			req.Header.Add(key, line)
+		}
 	}
 
-	if _, ok := headers["Accept-Encoding"]; !ok {
+	if key, _ := HeaderValue(headers, "Accept-Encoding"); key == "" {
 		req.Header.Set("Accept-Encoding", "gzip")
 	}
 
@@ -90,3 +114,39 @@ func (rs *RequestService) Post(url string, payload string, headers map[string]st
 
 	return string(body), respHeaders, nil
 }
+
+// FetchHeaders turns the headers of a request file into headers for a request the browser sends. Repeated
+// headers are joined and the file's cookies are sent along with the context cookies, the file's win.
+func (rs *RequestService) FetchHeaders(request map[string]string, cookies []playwright.Cookie) map[string]string {
+	headers := make(map[string]string)
+	for key, value := range request {
+		if strings.HasPrefix(key, "_ ") || browserSetHeaders[strings.ToLower(key)] {
+			continue
+		}
+		if strings.EqualFold(key, "Cookie") {
+			headers[key] = strings.ReplaceAll(value, "\n", "; ")
+		} else {
+			headers[key] = strings.ReplaceAll(value, "\n", ", ")
+		}
+	}
+
+	// without a Cookie header the browser sends the context cookies itself
+	key, value := HeaderValue(headers, "Cookie")
+	if key == "" {
+		return headers
+	}
+
+	names := make(map[string]struct{})
+	for _, pair := range strings.Split(value, ";") {
+		name, _, _ := strings.Cut(strings.TrimSpace(pair), "=")
+		names[name] = struct{}{}
+	}
+	for _, cookie := range cookies {
+		if _, exists := names[cookie.Name]; !exists {
+			value += "; " + cookie.Name + "=" + cookie.Value
+		}
+	}
+	headers[key] = value
+
+	return headers
+}
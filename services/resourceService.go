@@ -0,0 +1,243 @@
+package services
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"xss/utils"
+
+	"github.com/playwright-community/playwright-go"
+)
+
+// blockableResources are the resource types that can be left out, scripts and documents are what the payloads
+// run in and are always loaded
+var blockableResources = map[string]struct{}{
+	"image":      {},
+	"media":      {},
+	"font":       {},
+	"stylesheet": {},
+	"texttrack":  {},
+	"manifest":   {},
+	"other":      {},
+}
+
+// controlEvery loads the blocked resource types on every nth scanned page, its load time is the baseline the speedup
+// is measured against. The --block-url patterns stay blocked on it, they may name requests that must never go out.
+const controlEvery = 20
+
+// loadTimes sums page load durations
+type loadTimes struct {
+	count int
+	total time.Duration
+}
+
+func (lt loadTimes) average() time.Duration {
+	if lt.count == 0 {
+		return 0
+	}
+	return lt.total / time.Duration(lt.count)
+}
+
+type ResourceService struct {
+	argService *ArgsService
+
+	types    map[string]struct{}
+	patterns []*regexp.Regexp
+
+	pages    int
+	controls map[playwright.Page]struct{}
+	blocked  map[string]int
+	loads    loadTimes
+	baseline loadTimes
+	m        sync.Mutex
+}
+
+var resourceServiceInstance *ResourceService = nil
+
+// Singleton instance of ResourceService
+func GetResourceService() (*ResourceService, error) {
+	if resourceServiceInstance == nil {
+		var argService *ArgsService
+		var err error
+
+		if argService, err = GetArgsService(); err != nil {
+			return nil, err
+		}
+
+		rs := &ResourceService{
+			argService: argService,
+			types:      make(map[string]struct{}),
+			controls:   make(map[playwright.Page]struct{}),
+			blocked:    make(map[string]int),
+		}
+
+		options := argService.GetAll()
+
+		// types are given one per flag or comma separated
+		types, _ := options[ArgKeys.Resource].([]string)
+		for _, value := range types {
+			for _, resourceType := range strings.Split(value, ",") {
+				resourceType = strings.ToLower(strings.TrimSpace(resourceType))
+				if resourceType == "" {
+					continue
+				}
+				if _, ok := blockableResources[resourceType]; !ok {
+					var names []string
+					for name := range blockableResources {
+						names = append(names, name)
+					}
+					sort.Strings(names)
+					return nil, fmt.Errorf("resource type %q can't be blocked, expected one of: %s", resourceType, strings.Join(names, ", "))
+				}
+				rs.types[resourceType] = struct{}{}
+			}
+		}
+
+		patterns, _ := options[ArgKeys.BlockUrl].([]string)
+		for _, pattern := range patterns {
+			compiled, err := regexp.Compile(pattern)
+			if err != nil {
+				return nil, fmt.Errorf("invalid --%s pattern %q: %w", ArgKeys.BlockUrl, pattern, err)
+			}
+			rs.patterns = append(rs.patterns, compiled)
+		}
+
+		resourceServiceInstance = rs
+	}
+
+	return resourceServiceInstance, nil
+}
+
+// Enabled reports whether any resource type or URL pattern is blocked
+func (rs *ResourceService) Enabled() bool {
+	return len(rs.types) > 0 || len(rs.patterns) > 0
+}
+
+// Blocks reports whether a request of the resource type to the URL is left out, a control page loads the
+// blocked types but not the URLs the patterns match
+func (rs *ResourceService) Blocks(resourceType string, rawUrl string, control bool) bool {
+	if resourceType == "document" || resourceType == "script" {
+		return false
+	}
+
+	for _, pattern := range rs.patterns {
+		if pattern.MatchString(rawUrl) {
+			return true
+		}
+	}
+	if _, ok := rs.types[resourceType]; ok {
+		return !control
+	}
+	return false
+}
+
+// Apply aborts the blocked requests of the context, everything else falls through to the other handlers
+func (rs *ResourceService) Apply(ctx playwright.BrowserContext) error {
+	if !rs.Enabled() {
+		return nil
+	}
+
+	return ctx.Route("**/*", func(route playwright.Route) {
+		request := route.Request()
+		if !rs.Blocked(request) {
+			route.Fallback()
+			return
+		}
+
+		rs.m.Lock()
+		rs.blocked[request.ResourceType()]++
+		rs.m.Unlock()
+
+		route.Abort("blockedbyclient")
+	})
+}
+
+// Blocked reports whether the request is left out, taking the control page it may come from into account
+func (rs *ResourceService) Blocked(request playwright.Request) bool {
+	return rs.Enabled() && rs.Blocks(request.ResourceType(), request.URL(), rs.isControl(request))
+}
+
+func (rs *ResourceService) isControl(request playwright.Request) bool {
+	frame := request.Frame()
+	if frame == nil {
+		return false
+	}
+
+	rs.m.Lock()
+	defer rs.m.Unlock()
+
+	_, control := rs.controls[frame.Page()]
+	return control
+}
+
+// Track registers a scan page before it loads, every controlEvery one is a control page loading the blocked
+// resource types. With only URL patterns blocked there is nothing a control page would load.
+func (rs *ResourceService) Track(page playwright.Page) {
+	if !rs.Enabled() {
+		return
+	}
+
+	rs.m.Lock()
+	defer rs.m.Unlock()
+
+	rs.pages++
+	if len(rs.types) > 0 && rs.pages%controlEvery == 0 {
+		rs.controls[page] = struct{}{}
+		page.Once("close", func(playwright.Page) {
+			rs.m.Lock()
+			defer rs.m.Unlock()
+
+			delete(rs.controls, page)
+		})
+	}
+}
+
+// Loaded records how long a tracked page took to load
+func (rs *ResourceService) Loaded(page playwright.Page, duration time.Duration) {
+	if !rs.Enabled() {
+		return
+	}
+
+	rs.m.Lock()
+	defer rs.m.Unlock()
+
+	loads := &rs.loads
+	if _, control := rs.controls[page]; control {
+		loads = &rs.baseline
+	}
+	loads.count++
+	loads.total += duration
+}
+
+// Summary logs the blocked requests by type and the page load time against the control pages
+func (rs *ResourceService) Summary() {
+	if !rs.Enabled() {
+		return
+	}
+
+	rs.m.Lock()
+	defer rs.m.Unlock()
+
+	var types []string
+	total := 0
+	for resourceType, count := range rs.blocked {
+		types = append(types, fmt.Sprintf("%s [%d]", resourceType, count))
+		total += count
+	}
+	sort.Strings(types)
+	utils.Log.Info(fmt.Sprintf("Blocked resources: [%d] %s", total, strings.Join(types, ", ")))
+
+	if rs.loads.count == 0 || rs.baseline.count == 0 {
+		utils.Log.Info(fmt.Sprintf("Average page load: [%s] over [%d] pages, no control pages for a baseline", rs.loads.average().Round(time.Millisecond), rs.loads.count))
+		return
+	}
+
+	blocked, baseline := rs.loads.average(), rs.baseline.average()
+	speedup := float64(baseline) / float64(max(blocked, time.Millisecond))
+	saved := (baseline - blocked) * time.Duration(rs.loads.count)
+	utils.Log.Info(fmt.Sprintf("Average page load: [%s] blocked over [%d] pages, [%s] with the resource types loaded over [%d] control pages, %.1fx faster, about [%s] saved",
+		blocked.Round(time.Millisecond), rs.loads.count, baseline.Round(time.Millisecond), rs.baseline.count, speedup, saved.Round(time.Second)))
+}
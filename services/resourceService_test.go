@@ -0,0 +1,38 @@
+package services
+
+import (
+	"regexp"
+	"testing"
+)
+
+func TestResourceBlocks(t *testing.T) {
+	rs := &ResourceService{
+		types:    map[string]struct{}{"image": {}, "font": {}},
+		patterns: []*regexp.Regexp{regexp.MustCompile(`analytics\.example\.com`)},
+	}
+
+	tests := []struct {
+		resourceType string
+		url          string
+		control      bool
+		want         bool
+	}{
+		{"image", "https://example.com/a.png", false, true},
+		{"font", "https://example.com/a.woff", false, true},
+		{"stylesheet", "https://example.com/a.css", false, false},
+		{"xhr", "https://analytics.example.com/collect", false, true},
+		// control pages load the blocked types for the baseline, the patterns stay blocked
+		{"image", "https://example.com/a.png", true, false},
+		{"xhr", "https://analytics.example.com/collect", true, true},
+		{"image", "https://analytics.example.com/pixel.gif", true, true},
+		// documents and scripts are what the payloads run in
+		{"script", "https://analytics.example.com/tag.js", false, false},
+		{"document", "https://analytics.example.com/", false, false},
+	}
+
+	for _, test := range tests {
+		if got := rs.Blocks(test.resourceType, test.url, test.control); got != test.want {
+			t.Errorf("Blocks(%s, %s, control %t) = %t, want %t", test.resourceType, test.url, test.control, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,223 @@
+package services
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+	"xss/utils"
+
+	"github.com/playwright-community/playwright-go"
+	"golang.org/x/net/publicsuffix"
+)
+
+// Reasons a request is blocked for
+const (
+	BlockOutOfScope = "out of scope"
+	BlockThirdParty = "third party"
+)
+
+type ScopeService struct {
+	argService *ArgsService
+
+	includeHosts []*regexp.Regexp
+	excludeHosts []*regexp.Regexp
+	includePaths []*regexp.Regexp
+	excludePaths []*regexp.Regexp
+	thirdParty   bool
+
+	// sites are the registrable domains of the scanned targets, every other one is a third party
+	sites   map[string]struct{}
+	blocked map[string]int
+	logged  *utils.HashSet
+	m       sync.RWMutex
+}
+
+var scopeServiceInstance *ScopeService = nil
+
+// Singleton instance of ScopeService
+func GetScopeService() (*ScopeService, error) {
+	if scopeServiceInstance == nil {
+		var argService *ArgsService
+		var err error
+
+		if argService, err = GetArgsService(); err != nil {
+			return nil, err
+		}
+
+		ss := &ScopeService{
+			argService: argService,
+			sites:      make(map[string]struct{}),
+			blocked:    make(map[string]int),
+			logged:     utils.NewHashSet(),
+		}
+
+		options := argService.GetAll()
+		ss.thirdParty, _ = options[ArgKeys.ThirdPty].(bool)
+
+		rules := []struct {
+			key     ArgKey
+			compile *[]*regexp.Regexp
+		}{
+			{ArgKeys.InHosts, &ss.includeHosts},
+			{ArgKeys.ExHosts, &ss.excludeHosts},
+			{ArgKeys.InPaths, &ss.includePaths},
+			{ArgKeys.ExPaths, &ss.excludePaths},
+		}
+		for _, rule := range rules {
+			patterns, _ := options[rule.key].([]string)
+			for _, pattern := range patterns {
+				compiled, err := regexp.Compile(pattern)
+				if err != nil {
+					return nil, fmt.Errorf("invalid --%s pattern %q: %w", rule.key, pattern, err)
+				}
+				*rule.compile = append(*rule.compile, compiled)
+			}
+		}
+
+		scopeServiceInstance = ss
+	}
+
+	return scopeServiceInstance, nil
+}
+
+// HasRules reports whether any include or exclude rule was given
+func (ss *ScopeService) HasRules() bool {
+	return len(ss.includeHosts)+len(ss.excludeHosts)+len(ss.includePaths)+len(ss.excludePaths) > 0
+}
+
+// Enabled reports whether the browser requests have to be routed through the scope check
+func (ss *ScopeService) Enabled() bool {
+	return ss.HasRules() || ss.thirdParty
+}
+
+// InScope reports whether the URL passes the host and path rules. Hosts are matched lowercase without the port,
+// paths as they are escaped. A URL the rules can't apply to, like data: or about:blank, is in scope.
+func (ss *ScopeService) InScope(rawUrl string) bool {
+	parsed, err := url.Parse(StripInjectionPoints(rawUrl))
+	if err != nil || parsed.Host == "" {
+		return true
+	}
+
+	host := strings.ToLower(parsed.Hostname())
+	path := parsed.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+
+	return ss.passes(host, ss.includeHosts, ss.excludeHosts) && ss.passes(path, ss.includePaths, ss.excludePaths)
+}
+
+func (ss *ScopeService) passes(value string, include []*regexp.Regexp, exclude []*regexp.Regexp) bool {
+	if len(include) > 0 && !ss.matches(value, include) {
+		return false
+	}
+	return !ss.matches(value, exclude)
+}
+
+func (ss *ScopeService) matches(value string, patterns []*regexp.Regexp) bool {
+	for _, pattern := range patterns {
+		if pattern.MatchString(value) {
+			return true
+		}
+	}
+	return false
+}
+
+// AddSites records the sites of the scanned targets, requests to any other site are third party
+func (ss *ScopeService) AddSites(urls []string) {
+	ss.m.Lock()
+	defer ss.m.Unlock()
+
+	for _, rawUrl := range urls {
+		if parsed, err := url.Parse(StripInjectionPoints(rawUrl)); err == nil && parsed.Host != "" {
+			ss.sites[ss.site(parsed.Hostname())] = struct{}{}
+		}
+	}
+}
+
+// site returns the registrable domain of the host, www.shop.example.co.uk is example.co.uk. IP addresses and
+// single label hosts are sites of their own.
+func (ss *ScopeService) site(host string) string {
+	host = strings.ToLower(host)
+	if site, err := publicsuffix.EffectiveTLDPlusOne(host); err == nil {
+		return site
+	}
+	return host
+}
+
+// FirstParty reports whether the URL belongs to the site of a scanned target or a host the include rules name
+func (ss *ScopeService) FirstParty(rawUrl string) bool {
+	parsed, err := url.Parse(rawUrl)
+	if err != nil || parsed.Host == "" {
+		return true
+	}
+
+	host := strings.ToLower(parsed.Hostname())
+	if len(ss.includeHosts) > 0 && ss.matches(host, ss.includeHosts) {
+		return true
+	}
+
+	ss.m.RLock()
+	defer ss.m.RUnlock()
+
+	if len(ss.sites) == 0 {
+		return true
+	}
+	_, exists := ss.sites[ss.site(host)]
+	return exists
+}
+
+// Block returns why the browser must not load the URL, empty when it may
+func (ss *ScopeService) Block(rawUrl string) string {
+	if !ss.InScope(rawUrl) {
+		return BlockOutOfScope
+	}
+	if ss.thirdParty && !ss.FirstParty(rawUrl) {
+		return BlockThirdParty
+	}
+	return ""
+}
+
+// Apply routes every request of the context through the scope check, blocked ones are aborted and the rest
+// fall through to the other handlers
+func (ss *ScopeService) Apply(ctx playwright.BrowserContext) error {
+	if !ss.Enabled() {
+		return nil
+	}
+
+	return ctx.Route("**/*", func(route playwright.Route) {
+		request := route.Request()
+		if reason := ss.Block(request.URL()); reason != "" {
+			ss.record(reason, request)
+			route.Abort("blockedbyclient")
+			return
+		}
+		route.Fallback()
+	})
+}
+
+// record counts a blocked request and logs every blocked URL once
+func (ss *ScopeService) record(reason string, request playwright.Request) {
+	ss.m.Lock()
+	defer ss.m.Unlock()
+
+	ss.blocked[reason]++
+	if ss.logged.Add(request.URL()) {
+		utils.Log.Debug(fmt.Sprintf("Blocked %s %s request: %s", reason, request.ResourceType(), request.URL()))
+	}
+}
+
+// Summary logs how many requests were blocked for each reason
+func (ss *ScopeService) Summary() {
+	if !ss.Enabled() {
+		return
+	}
+
+	ss.m.RLock()
+	defer ss.m.RUnlock()
+
+	utils.Log.Info(fmt.Sprintf("Blocked requests: out of scope [%d], third party [%d], distinct URLs [%d]",
+		ss.blocked[BlockOutOfScope], ss.blocked[BlockThirdParty], ss.logged.Len()))
+}
@@ -0,0 +1,259 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+	"xss/utils"
+
+	"github.com/playwright-community/playwright-go"
+)
+
+type StoredService struct {
+	VerifyUrls []string
+	Crawl      bool
+
+	argService      *ArgsService
+	fileService     *FileService
+	urlService      *UrlService
+	monitorService  *MonitorService
+	ledgerService   *LedgerService
+	reportService   *ReportService
+	identityService *IdentityService
+
+	reported map[string]struct{}
+	started  bool
+	m        sync.Mutex
+}
+
+var storedServiceInstance *StoredService = nil
+
+// Singleton instance of StoredService
+func GetStoredService() (*StoredService, error) {
+	if storedServiceInstance == nil {
+		var argService *ArgsService
+		var fileService *FileService
+		var urlService *UrlService
+		var monitorService *MonitorService
+		var ledgerService *LedgerService
+		var reportService *ReportService
+		var identityService *IdentityService
+		var err error
+
+		if argService, err = GetArgsService(); err != nil {
+			return nil, err
+		}
+
+		if fileService, err = GetFileService(); err != nil {
+			return nil, err
+		}
+
+		if urlService, err = GetUrlService(); err != nil {
+			return nil, err
+		}
+
+		if monitorService, err = GetMonitorService(); err != nil {
+			return nil, err
+		}
+
+		if ledgerService, err = GetLedgerService(); err != nil {
+			return nil, err
+		}
+
+		if reportService, err = GetReportService(); err != nil {
+			return nil, err
+		}
+
+		if identityService, err = GetIdentityService(); err != nil {
+			return nil, err
+		}
+
+		storedServiceInstance = &StoredService{
+			argService:      argService,
+			fileService:     fileService,
+			urlService:      urlService,
+			monitorService:  monitorService,
+			ledgerService:   ledgerService,
+			reportService:   reportService,
+			identityService: identityService,
+			reported:        make(map[string]struct{}),
+		}
+	}
+
+	return storedServiceInstance, nil
+}
+
+// Run reads the verification URLs and prepares the canary ledger for the scan UUID
+func (ss *StoredService) Run(uuid string) error {
+	ss.started = true
+
+	if crawl, err := ss.argService.Get(ArgKeys.Crawl); err == nil {
+		ss.Crawl = crawl.(bool)
+	}
+
+	verifyFile, err := ss.argService.Get(ArgKeys.Verify)
+	if err != nil || verifyFile.(string) == "" {
+		if ss.Crawl {
+			// the second-order sweep still needs a canary per injection
+			return ss.ledgerService.Init(uuid)
+		}
+		return nil
+	}
+
+	utils.Log.Info("Running StoredService")
+
+	fileContent, err := ss.fileService.ReadFileAsString(verifyFile.(string))
+	if err != nil {
+		return err
+	}
+
+	var verifyUrls []string
+	for _, line := range strings.Split(fileContent, "\n") {
+		line = strings.Trim(line, " \r")
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		if ss.urlService.ValidateUrl(line) {
+			verifyUrls = append(verifyUrls, line)
+		} else {
+			utils.Log.Error("Invalid verification URL:", line)
+		}
+	}
+
+	if err := ss.ledgerService.Init(uuid); err != nil {
+		return err
+	}
+
+	ss.VerifyUrls = utils.RemoveDuplicates(verifyUrls)
+	utils.Log.Info(fmt.Sprintf("Verification URLs parsed: [%d]", len(ss.VerifyUrls)))
+
+	return nil
+}
+
+// Started reports whether Run was called, a scan of both GET targets and requests shares one ledger
+func (ss *StoredService) Started() bool {
+	return ss.started
+}
+
+// Enabled reports whether injections get their own canary, either for verification pages or the crawl sweep
+func (ss *StoredService) Enabled() bool {
+	return len(ss.VerifyUrls) > 0 || ss.Crawl
+}
+
+// Inject records an injection in the ledger and returns the canary to put in place of the scan UUID
+func (ss *StoredService) Inject(target string, location string, param string) (string, error) {
+	return ss.ledgerService.Issue(LedgerEntry{
+		Target:   target,
+		Location: location,
+		Param:    param,
+		Role:     ss.identityService.Injector().Name,
+	})
+}
+
+// Verify visits every verification URL as every verifying identity and records the stored payloads that fire there
+func (ss *StoredService) Verify() int {
+	found := 0
+
+	for _, identity := range ss.identityService.Verifiers() {
+		for _, pageUrl := range ss.VerifyUrls {
+			hits, err := ss.VerifyPage(identity.Ctx, pageUrl)
+			if err != nil {
+				utils.Log.Error(fmt.Sprintf("Error visiting verification page %s: %s", pageUrl, err))
+				continue
+			}
+
+			found += ss.Record(hits, pageUrl, identity.Name)
+		}
+	}
+
+	return found
+}
+
+// VerifyPage loads a single page with the monitors attached and returns what fired
+func (ss *StoredService) VerifyPage(ctx playwright.BrowserContext, pageUrl string) ([]MonitorHit, error) {
+	options := ss.argService.GetAll()
+	timeout := float64(options[ArgKeys.Timeout].(int))
+
+	page, err := ctx.NewPage()
+	if err != nil {
+		return nil, err
+	}
+	defer page.Close()
+
+	monitor, err := ss.monitorService.Attach(page, ss.ledgerService.Marker())
+	if err != nil {
+		return nil, err
+	}
+	defer ss.monitorService.Detach(monitor)
+
+	if _, err := page.Goto(pageUrl, playwright.PageGotoOptions{Timeout: playwright.Float(timeout)}); err != nil {
+		return nil, err
+	}
+
+	return ss.monitorService.Wait(monitor, time.Duration(1000)*time.Millisecond), nil
+}
+
+// Record ties the hits seen by the given role back to their injections and adds the ones not reported yet,
+// returns how many were new
+func (ss *StoredService) Record(hits []MonitorHit, pageUrl string, role string) int {
+	found := 0
+
+	for _, hit := range hits {
+		entries := ss.ledgerService.Match(hit.Value)
+		if len(entries) == 0 && !hit.Sink {
+			utils.Log.Warn(fmt.Sprintf("Alert without a known canary on %s: %s", pageUrl, hit.Value))
+			continue
+		}
+
+		for _, entry := range entries {
+			key := entry.Canary + " " + pageUrl + " " + role
+
+			ss.m.Lock()
+			_, exists := ss.reported[key]
+			ss.reported[key] = struct{}{}
+			ss.m.Unlock()
+
+			if exists {
+				continue
+			}
+
+			utils.Log.Success(fmt.Sprintf("Stored XSS found: %s fired on %s (%s)", entry.Target, pageUrl, hit.Kind))
+			ss.reportService.AddFinding(Finding{
+				Target:   entry.Target,
+				Location: entry.Location,
+				Param:    entry.Param,
+				Trigger:  hit.Kind,
+				Page:     pageUrl,
+				Canary:   entry.Canary,
+
+				InjectedAs:  entry.Role,
+				TriggeredAs: role,
+			})
+			found++
+		}
+	}
+
+	return found
+}
+
+// Sweep revisits every verification page once all injections are done, catching payloads that render late
+func (ss *StoredService) Sweep() error {
+	options := ss.argService.GetAll()
+
+	utils.Log.Info(fmt.Sprintf("Sweeping [%d] verification URLs for [%d] injections", len(ss.VerifyUrls), ss.ledgerService.Len()))
+	found := ss.Verify()
+
+	if found > 0 {
+		utils.Log.Success(fmt.Sprintf("Stored XSS found in final sweep: %d", found))
+	} else {
+		utils.Log.Info("No new stored XSS found in final sweep")
+	}
+
+	if options[ArgKeys.Report].(string) != "" {
+		return ss.reportService.SaveReport(options[ArgKeys.Report].(string))
+	}
+
+	return nil
+}
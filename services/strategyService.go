@@ -0,0 +1,221 @@
+package services
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"xss/utils"
+)
+
+// Attack strategies for templates with named injection points
+const (
+	StrategySniper       = "sniper"
+	StrategyBatteringRam = "battering-ram"
+	StrategyPitchfork    = "pitchfork"
+	StrategyClusterBomb  = "cluster-bomb"
+)
+
+// pointPattern matches named injection points, `{p1}` or `{p1:value}` where value is used when the point is not attacked
+var pointPattern = regexp.MustCompile(`\{(p\d+)(?::([^{}]*))?\}`)
+
+// Attack is one assignment of payloads to the injection points of a template
+type Attack struct {
+	Values  map[string]string
+	Targets []string
+}
+
+type StrategyService struct {
+	argService  *ArgsService
+	fileService *FileService
+}
+
+var strategyServiceInstance *StrategyService = nil
+
+// Singleton instance of StrategyService
+func GetStrategyService() (*StrategyService, error) {
+	if strategyServiceInstance == nil {
+		var argService *ArgsService
+		var fileService *FileService
+		var err error
+
+		if argService, err = GetArgsService(); err != nil {
+			return nil, err
+		}
+
+		if fileService, err = GetFileService(); err != nil {
+			return nil, err
+		}
+
+		strategyServiceInstance = &StrategyService{
+			argService:  argService,
+			fileService: fileService,
+		}
+	}
+
+	return strategyServiceInstance, nil
+}
+
+// HasInjectionPoints reports whether the text holds a `{payload}` marker or a named injection point
+func HasInjectionPoints(text string) bool {
+	return strings.Contains(text, "{payload}") || pointPattern.MatchString(text)
+}
+
+// StripInjectionPoints replaces every marker with its default value, for requests that should not carry payloads
+func StripInjectionPoints(text string) string {
+	text = strings.ReplaceAll(text, "{payload}", "")
+	return pointPattern.ReplaceAllString(text, "$2")
+}
+
+// Points returns the names of the injection points in the texts in order of appearance
+func (ss *StrategyService) Points(texts ...string) []string {
+	var points []string
+	for _, text := range texts {
+		for _, match := range pointPattern.FindAllStringSubmatch(text, -1) {
+			points = append(points, match[1])
+		}
+	}
+
+	return utils.RemoveDuplicates(points)
+}
+
+// Fill replaces the injection points of the text with the attack values, or their defaults when not attacked
+func (ss *StrategyService) Fill(text string, attack Attack) string {
+	return pointPattern.ReplaceAllStringFunc(text, func(point string) string {
+		match := pointPattern.FindStringSubmatch(point)
+		if value, ok := attack.Values[match[1]]; ok {
+			return value
+		}
+		return match[2]
+	})
+}
+
+// Encode returns a copy of the attack with every value encoded for the place it is filled into
+func (ss *StrategyService) Encode(attack Attack, encode func(string) string) Attack {
+	values := make(map[string]string, len(attack.Values))
+	for point, value := range attack.Values {
+		values[point] = encode(value)
+	}
+	return Attack{Values: values, Targets: attack.Targets}
+}
+
+// GetPointPayloads reads the per point payload files given as `p2=file.txt`, with ### replaced by the UUID
+func (ss *StrategyService) GetPointPayloads(uuid string) (map[string][]string, error) {
+	pointPayloads := make(map[string][]string)
+
+	specs, _ := ss.argService.GetAll()[ArgKeys.Points].([]string)
+	for _, spec := range specs {
+		point, path, ok := strings.Cut(spec, "=")
+		if !ok || !pointPattern.MatchString("{"+point+"}") || path == "" {
+			return nil, fmt.Errorf("invalid point payloads %q, expected p<n>=file", spec)
+		}
+
+		fileContent, err := ss.fileService.ReadFileAsString(path)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, payload := range strings.Split(fileContent, "\n") {
+			if strings.Trim(payload, " ") != "" {
+				pointPayloads[point] = append(pointPayloads[point], strings.Replace(payload, "###", uuid, -1))
+			}
+		}
+	}
+
+	return pointPayloads, nil
+}
+
+// Combine assigns payloads to the points with the configured strategy, up to the configured count.
+// Points without their own payload list use the default payloads.
+func (ss *StrategyService) Combine(points []string, payloads []string, pointPayloads map[string][]string) []Attack {
+	options := ss.argService.GetAll()
+	strategy := options[ArgKeys.Strategy].(string)
+	limit := options[ArgKeys.Combos].(int)
+
+	lists := make([][]string, len(points))
+	for ix, point := range points {
+		if list, ok := pointPayloads[point]; ok {
+			lists[ix] = list
+		} else {
+			lists[ix] = payloads
+		}
+	}
+
+	var attacks []Attack
+	full := func() bool {
+		return limit > 0 && len(attacks) >= limit
+	}
+
+	switch strategy {
+	case StrategyBatteringRam:
+		for _, payload := range payloads {
+			if full() {
+				break
+			}
+			values := make(map[string]string)
+			for _, point := range points {
+				values[point] = payload
+			}
+			attacks = append(attacks, Attack{Values: values, Targets: points})
+		}
+
+	case StrategyPitchfork:
+		count := 0
+		for ix, list := range lists {
+			if ix == 0 || len(list) < count {
+				count = len(list)
+			}
+		}
+		for i := 0; i < count && !full(); i++ {
+			values := make(map[string]string)
+			for ix, point := range points {
+				values[point] = lists[ix][i]
+			}
+			attacks = append(attacks, Attack{Values: values, Targets: points})
+		}
+
+	case StrategyClusterBomb:
+		for _, list := range lists {
+			if len(list) == 0 {
+				return nil
+			}
+		}
+		// odometer over the payload lists, the last point changes fastest
+		indexes := make([]int, len(points))
+		for len(points) > 0 && !full() {
+			values := make(map[string]string)
+			for ix, point := range points {
+				values[point] = lists[ix][indexes[ix]]
+			}
+			attacks = append(attacks, Attack{Values: values, Targets: points})
+
+			pos := len(indexes) - 1
+			for pos >= 0 {
+				indexes[pos]++
+				if indexes[pos] < len(lists[pos]) {
+					break
+				}
+				indexes[pos] = 0
+				pos--
+			}
+			if pos < 0 {
+				break
+			}
+		}
+
+	default:
+		for ix, point := range points {
+			for _, payload := range lists[ix] {
+				if full() {
+					break
+				}
+				attacks = append(attacks, Attack{Values: map[string]string{point: payload}, Targets: []string{point}})
+			}
+		}
+	}
+
+	if full() {
+		utils.Log.Warn(fmt.Sprintf("Strategy %s capped at %d combinations", strategy, limit))
+	}
+
+	return attacks
+}
@@ -0,0 +1,199 @@
+package services
+
+import (
+	"reflect"
+	"testing"
+)
+
+func newTestStrategyService(strategy string, limit int) *StrategyService {
+	return &StrategyService{argService: &ArgsService{argsMap: map[ArgKey]interface{}{
+		ArgKeys.Strategy: strategy,
+		ArgKeys.Combos:   limit,
+	}}}
+}
+
+func TestStrategyCombine(t *testing.T) {
+	both := []string{"p1", "p2"}
+
+	tests := []struct {
+		name          string
+		strategy      string
+		limit         int
+		points        []string
+		payloads      []string
+		pointPayloads map[string][]string
+		want          []Attack
+	}{
+		{
+			name:          "sniper attacks one point at a time with its own list",
+			strategy:      StrategySniper,
+			points:        both,
+			payloads:      []string{"a", "b"},
+			pointPayloads: map[string][]string{"p2": {"x"}},
+			want: []Attack{
+				{Values: map[string]string{"p1": "a"}, Targets: []string{"p1"}},
+				{Values: map[string]string{"p1": "b"}, Targets: []string{"p1"}},
+				{Values: map[string]string{"p2": "x"}, Targets: []string{"p2"}},
+			},
+		},
+		{
+			name:     "sniper capped",
+			strategy: StrategySniper,
+			limit:    3,
+			points:   both,
+			payloads: []string{"a", "b"},
+			want: []Attack{
+				{Values: map[string]string{"p1": "a"}, Targets: []string{"p1"}},
+				{Values: map[string]string{"p1": "b"}, Targets: []string{"p1"}},
+				{Values: map[string]string{"p2": "a"}, Targets: []string{"p2"}},
+			},
+		},
+		{
+			name:          "battering ram puts the same payload everywhere and ignores point lists",
+			strategy:      StrategyBatteringRam,
+			points:        both,
+			payloads:      []string{"a", "b"},
+			pointPayloads: map[string][]string{"p2": {"x"}},
+			want: []Attack{
+				{Values: map[string]string{"p1": "a", "p2": "a"}, Targets: both},
+				{Values: map[string]string{"p1": "b", "p2": "b"}, Targets: both},
+			},
+		},
+		{
+			name:     "battering ram capped",
+			strategy: StrategyBatteringRam,
+			limit:    1,
+			points:   both,
+			payloads: []string{"a", "b"},
+			want: []Attack{
+				{Values: map[string]string{"p1": "a", "p2": "a"}, Targets: both},
+			},
+		},
+		{
+			name:          "pitchfork stops at the shortest list",
+			strategy:      StrategyPitchfork,
+			points:        both,
+			payloads:      []string{"a", "b", "c"},
+			pointPayloads: map[string][]string{"p2": {"x", "y"}},
+			want: []Attack{
+				{Values: map[string]string{"p1": "a", "p2": "x"}, Targets: both},
+				{Values: map[string]string{"p1": "b", "p2": "y"}, Targets: both},
+			},
+		},
+		{
+			name:          "pitchfork with the shorter list first",
+			strategy:      StrategyPitchfork,
+			points:        both,
+			payloads:      []string{"a", "b", "c"},
+			pointPayloads: map[string][]string{"p1": {"x"}},
+			want: []Attack{
+				{Values: map[string]string{"p1": "x", "p2": "a"}, Targets: both},
+			},
+		},
+		{
+			name:          "pitchfork capped",
+			strategy:      StrategyPitchfork,
+			limit:         1,
+			points:        both,
+			payloads:      []string{"a", "b", "c"},
+			pointPayloads: map[string][]string{"p2": {"x", "y"}},
+			want: []Attack{
+				{Values: map[string]string{"p1": "a", "p2": "x"}, Targets: both},
+			},
+		},
+		{
+			name:          "cluster bomb tries every combination, the last point fastest",
+			strategy:      StrategyClusterBomb,
+			points:        both,
+			payloads:      []string{"a", "b"},
+			pointPayloads: map[string][]string{"p2": {"x", "y", "z"}},
+			want: []Attack{
+				{Values: map[string]string{"p1": "a", "p2": "x"}, Targets: both},
+				{Values: map[string]string{"p1": "a", "p2": "y"}, Targets: both},
+				{Values: map[string]string{"p1": "a", "p2": "z"}, Targets: both},
+				{Values: map[string]string{"p1": "b", "p2": "x"}, Targets: both},
+				{Values: map[string]string{"p1": "b", "p2": "y"}, Targets: both},
+				{Values: map[string]string{"p1": "b", "p2": "z"}, Targets: both},
+			},
+		},
+		{
+			name:          "cluster bomb capped",
+			strategy:      StrategyClusterBomb,
+			limit:         4,
+			points:        both,
+			payloads:      []string{"a", "b"},
+			pointPayloads: map[string][]string{"p2": {"x", "y", "z"}},
+			want: []Attack{
+				{Values: map[string]string{"p1": "a", "p2": "x"}, Targets: both},
+				{Values: map[string]string{"p1": "a", "p2": "y"}, Targets: both},
+				{Values: map[string]string{"p1": "a", "p2": "z"}, Targets: both},
+				{Values: map[string]string{"p1": "b", "p2": "x"}, Targets: both},
+			},
+		},
+		{
+			name:          "cluster bomb with an empty list",
+			strategy:      StrategyClusterBomb,
+			points:        both,
+			payloads:      []string{"a", "b"},
+			pointPayloads: map[string][]string{"p2": {}},
+			want:          nil,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ss := newTestStrategyService(test.strategy, test.limit)
+
+			got := ss.Combine(test.points, test.payloads, test.pointPayloads)
+			if len(got) != len(test.want) {
+				t.Fatalf("Combine() returned %d attacks, want %d", len(got), len(test.want))
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("Combine() =\n%v\nwant\n%v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestStrategyCombineCounts(t *testing.T) {
+	payloads := make([]string, 10)
+	for ix := range payloads {
+		payloads[ix] = string(rune('a' + ix))
+	}
+	points := []string{"p1", "p2", "p3"}
+
+	tests := []struct {
+		strategy string
+		limit    int
+		want     int
+	}{
+		{StrategySniper, 0, 30},
+		{StrategyBatteringRam, 0, 10},
+		{StrategyPitchfork, 0, 10},
+		{StrategyClusterBomb, 0, 1000},
+		{StrategySniper, 25, 25},
+		{StrategyBatteringRam, 25, 10},
+		{StrategyClusterBomb, 250, 250},
+	}
+
+	for _, test := range tests {
+		ss := newTestStrategyService(test.strategy, test.limit)
+		if got := len(ss.Combine(points, payloads, nil)); got != test.want {
+			t.Errorf("%s with limit %d: %d attacks, want %d", test.strategy, test.limit, got, test.want)
+		}
+	}
+}
+
+func TestStrategyFill(t *testing.T) {
+	ss := newTestStrategyService(StrategySniper, 0)
+
+	template := "https://example.com/?a={p1}&b={p2:def}&c={p3}"
+	if got := ss.Points(template, "{p2}"); !reflect.DeepEqual(got, []string{"p1", "p2", "p3"}) {
+		t.Errorf("Points() = %v", got)
+	}
+
+	attack := Attack{Values: map[string]string{"p1": "x"}, Targets: []string{"p1"}}
+	if got, want := ss.Fill(template, attack), "https://example.com/?a=x&b=def&c="; got != want {
+		t.Errorf("Fill() = %s, want %s", got, want)
+	}
+}
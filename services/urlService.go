@@ -2,12 +2,29 @@ package services
 
 import (
 	"fmt"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
 	"strings"
 	"xss/utils"
 
 	urlutil "github.com/projectdiscovery/utils/url"
 )
 
+// Injection is a single generated GET target and the place its payload was put in
+type Injection struct {
+	Url      string
+	Location string
+	Param    string
+	Variant  string
+	Headers  map[string]string
+	Cookies  map[string]string
+}
+
+// paramNameEscaper keeps a payload used as a parameter name from splitting the query
+var paramNameEscaper = strings.NewReplacer("=", "%3D", "&", "%26", "#", "%23")
+
 type UrlService struct{}
 
 var urlServiceInstance *UrlService = nil
@@ -21,6 +38,22 @@ func GetUrlService() (*UrlService, error) {
 	return urlServiceInstance, nil
 }
 
+// EscapePayload query escapes the payload only when it would otherwise break the query string
+func (us *UrlService) EscapePayload(payload string) string {
+	if _, err := url.ParseQuery(fmt.Sprintf("?id=%s", payload)); err != nil {
+		return url.QueryEscape(payload)
+	}
+	return payload
+}
+
+// literalUrlPattern tells a URL given on the command line from a file path
+var literalUrlPattern = regexp.MustCompile(`^[a-zA-Z][a-zA-Z0-9+.-]*://`)
+
+// IsLiteralUrl reports whether a -u value is a URL to scan rather than a file to read
+func (us *UrlService) IsLiteralUrl(source string) bool {
+	return literalUrlPattern.MatchString(source)
+}
+
 // Validate URL
 func (us *UrlService) ValidateUrl(url string) bool {
 	if _, err := urlutil.Parse(url); err != nil {
@@ -29,8 +62,136 @@ func (us *UrlService) ValidateUrl(url string) bool {
 	return true
 }
 
-func (us *UrlService) CombineUrlQueryWithPayload(url string, payloads []string) []string {
-	combinedUrls := []string{}
+// Origin returns the scheme and host of the URL with a / path, or "" when it has no host
+func (us *UrlService) Origin(rawUrl string) string {
+	parsed, err := url.Parse(StripInjectionPoints(rawUrl))
+	if err != nil || parsed.Host == "" {
+		return ""
+	}
+	return parsed.Scheme + "://" + parsed.Host + "/"
+}
+
+// percentEscapePattern matches escapes so %2f and %2F compare equal
+var percentEscapePattern = regexp.MustCompile(`%[0-9a-fA-F]{2}`)
+
+// Normalize returns the form two URLs requesting the same page share: lowercase scheme and host, no default port,
+// no trailing slash, sorted query pairs and no fragment unless it is a client side route (#/ or #!/) or holds a payload
+// point. The path keeps its case, servers may tell /Item from /item. It is a key for deduplication only,
+// the targets keep the URL as written.
+func (us *UrlService) Normalize(rawUrl string) string {
+	rest, fragment, hasFragment := strings.Cut(rawUrl, "#")
+	rest, queryString, _ := strings.Cut(rest, "?")
+
+	scheme, rest, found := strings.Cut(rest, "://")
+	if !found {
+		return rawUrl
+	}
+	scheme = strings.ToLower(scheme)
+
+	authority, path := rest, "/"
+	if slash := strings.Index(rest, "/"); slash >= 0 {
+		authority, path = rest[:slash], rest[slash:]
+	}
+	userinfo, host := "", authority
+	if at := strings.LastIndex(authority, "@"); at >= 0 {
+		userinfo, host = authority[:at+1], authority[at+1:]
+	}
+	host = strings.ToLower(host)
+	switch scheme {
+	case "http":
+		host = strings.TrimSuffix(host, ":80")
+	case "https":
+		host = strings.TrimSuffix(host, ":443")
+	}
+
+	path = percentEscapePattern.ReplaceAllStringFunc(path, strings.ToUpper)
+	if len(path) > 1 {
+		path = strings.TrimSuffix(path, "/")
+	}
+
+	var pairs []string
+	for _, pair := range strings.Split(queryString, "&") {
+		if pair != "" {
+			pairs = append(pairs, percentEscapePattern.ReplaceAllStringFunc(pair, strings.ToUpper))
+		}
+	}
+	// repeated parameters keep their order, only the names are sorted
+	sort.SliceStable(pairs, func(i, j int) bool {
+		nameI, _, _ := strings.Cut(pairs[i], "=")
+		nameJ, _, _ := strings.Cut(pairs[j], "=")
+		return nameI < nameJ
+	})
+
+	normalized := scheme + "://" + userinfo + host + path
+	if len(pairs) > 0 {
+		normalized += "?" + strings.Join(pairs, "&")
+	}
+	if hasFragment && (strings.HasPrefix(fragment, "/") || strings.HasPrefix(fragment, "!/") || strings.Contains(fragment, "{")) {
+		normalized += "#" + fragment
+	}
+
+	return normalized
+}
+
+// Patterns of path segments that are identifiers rather than names, checked in order
+var shapeSegmentPatterns = []struct {
+	pattern     *regexp.Regexp
+	placeholder string
+}{
+	{regexp.MustCompile(`^\d+$`), "{int}"},
+	{regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`), "{uuid}"},
+	{regexp.MustCompile(`^[0-9a-fA-F]*\d[0-9a-fA-F]*$`), "{hex}"},
+	{regexp.MustCompile(`^[\w-]*\d[\w-]*$`), "{id}"},
+}
+
+// Shape returns the endpoint a URL belongs to: host, path with identifier segments generalized and the sorted
+// parameter names. /product/123?ref=a and /product/456?ref=b share a shape
+func (us *UrlService) Shape(rawUrl string) string {
+	normalized := us.Normalize(rawUrl)
+	normalized, _, _ = strings.Cut(normalized, "#")
+	rest, queryString, _ := strings.Cut(normalized, "?")
+
+	segments := strings.Split(rest, "/")
+	// segments 0 to 2 are the scheme and host
+	for i := 3; i < len(segments); i++ {
+		segments[i] = us.shapeSegment(segments[i])
+	}
+
+	var names []string
+	seen := make(map[string]struct{})
+	for _, pair := range strings.Split(queryString, "&") {
+		name, _, _ := strings.Cut(pair, "=")
+		if _, exists := seen[name]; name != "" && !exists {
+			seen[name] = struct{}{}
+			names = append(names, name)
+		}
+	}
+
+	return strings.Join(segments, "/") + "?" + strings.Join(names, "&")
+}
+
+// shapeSegment generalizes an identifier segment, an extension is kept so /123.html becomes /{int}.html
+func (us *UrlService) shapeSegment(segment string) string {
+	name, extension := segment, ""
+	if dot := strings.LastIndex(segment, "."); dot > 0 {
+		name, extension = segment[:dot], segment[dot:]
+	}
+
+	for _, shape := range shapeSegmentPatterns {
+		if shape.pattern.MatchString(name) {
+			// short hex words like "cafe1" or "v2" are more likely names than identifiers
+			if shape.placeholder == "{hex}" && len(name) < 8 || shape.placeholder == "{id}" && len(name) < 16 {
+				continue
+			}
+			return shape.placeholder + extension
+		}
+	}
+
+	return segment
+}
+
+func (us *UrlService) CombineUrlQueryWithPayload(url string, payloads []string) []Injection {
+	combinedUrls := []Injection{}
 
 	if !us.ValidateUrl(url) {
 		utils.Log.Error("Invalid URL:", url)
@@ -39,7 +200,7 @@ func (us *UrlService) CombineUrlQueryWithPayload(url string, payloads []string)
 
 	if strings.Contains(url, "{payload}") {
 		for _, payload := range payloads {
-			combinedUrls = append(combinedUrls, strings.ReplaceAll(url, "{payload}", payload))
+			combinedUrls = append(combinedUrls, Injection{Url: strings.ReplaceAll(url, "{payload}", payload), Location: "marker"})
 		}
 		return combinedUrls
 	} else {
@@ -52,7 +213,7 @@ func (us *UrlService) CombineUrlQueryWithPayload(url string, payloads []string)
 				for _, payload := range payloads {
 					clonedUrl, _ := urlutil.Parse(url)
 					clonedUrl.Query().Set(key, payload)
-					combinedUrls = append(combinedUrls, clonedUrl.String())
+					combinedUrls = append(combinedUrls, Injection{Url: clonedUrl.String(), Location: "query", Param: key})
 				}
 				return true
 			})
@@ -62,6 +223,187 @@ func (us *UrlService) CombineUrlQueryWithPayload(url string, payloads []string)
 	return combinedUrls
 }
 
+// CombineUrlParamWithPayload adds the parameter to the URL, or replaces it, with every payload
+func (us *UrlService) CombineUrlParamWithPayload(url string, key string, payloads []string) []Injection {
+	combinedUrls := []Injection{}
+
+	for _, payload := range payloads {
+		clonedUrl, err := urlutil.Parse(url)
+		if err != nil {
+			return combinedUrls
+		}
+		clonedUrl.Query().Set(key, payload)
+		combinedUrls = append(combinedUrls, Injection{Url: clonedUrl.String(), Location: "query", Param: key})
+	}
+
+	return combinedUrls
+}
+
+// CombineUrlParamNameWithPayload uses the payload as the parameter name with a benign value, once in place of
+// every existing parameter name and once as an extra parameter
+func (us *UrlService) CombineUrlParamNameWithPayload(rawUrl string, payloads []string) []Injection {
+	combinedUrls := []Injection{}
+
+	if strings.Contains(rawUrl, "{payload}") || !us.ValidateUrl(rawUrl) {
+		return combinedUrls
+	}
+
+	query := us.splitQuery(rawUrl)
+
+	for _, payload := range payloads {
+		key := paramNameEscaper.Replace(payload) + "=1"
+
+		for ix, pair := range query.pairs {
+			name, _, _ := strings.Cut(pair, "=")
+			newPairs := append([]string{}, query.pairs...)
+			newPairs[ix] = key
+			combinedUrls = append(combinedUrls, Injection{Url: query.build(newPairs, "&"), Location: "param-name", Param: name})
+		}
+
+		combinedUrls = append(combinedUrls, Injection{Url: query.build(append(append([]string{}, query.pairs...), key), "&"), Location: "param-name"})
+	}
+
+	return combinedUrls
+}
+
+// CombineUrlPollutionWithPayload generates parameter pollution variants for every query parameter: the payload
+// as a first or last duplicate, array and nested bracket names, and `;` separators mixed with `&`.
+// The payloads are raw, they are always query escaped so a `&`, `;` or `#` in one can't split the polluted pair.
+func (us *UrlService) CombineUrlPollutionWithPayload(rawUrl string, payloads []string) []Injection {
+	combinedUrls := []Injection{}
+
+	if strings.Contains(rawUrl, "{payload}") || !us.ValidateUrl(rawUrl) {
+		return combinedUrls
+	}
+
+	query := us.splitQuery(rawUrl)
+
+	for _, payload := range payloads {
+		payload = url.QueryEscape(payload)
+		for ix, pair := range query.pairs {
+			name, _, _ := strings.Cut(pair, "=")
+			injected := name + "=" + payload
+
+			variants := []struct {
+				name      string
+				pair      string
+				separator string
+			}{
+				{"duplicate-first", injected + "&" + pair, "&"},
+				{"duplicate-last", pair + "&" + injected, "&"},
+				{"array", name + "[]=" + payload, "&"},
+				{"array-index", name + "[0]=" + payload, "&"},
+				{"nested", name + "[" + name + "]=" + payload, "&"},
+				{"semicolon", injected, ";"},
+				{"semicolon-duplicate", pair + ";" + injected, "&"},
+			}
+
+			for _, variant := range variants {
+				newPairs := append([]string{}, query.pairs...)
+				newPairs[ix] = variant.pair
+				combinedUrls = append(combinedUrls, Injection{
+					Url:      query.build(newPairs, variant.separator),
+					Location: "query",
+					Param:    name,
+					Variant:  variant.name,
+				})
+			}
+		}
+	}
+
+	return combinedUrls
+}
+
+// rawQuery keeps the query pairs exactly as written so variants can duplicate and rename them
+type rawQuery struct {
+	base        string
+	pairs       []string
+	fragment    string
+	hasFragment bool
+}
+
+func (us *UrlService) splitQuery(rawUrl string) rawQuery {
+	rawUrl, fragment, hasFragment := strings.Cut(rawUrl, "#")
+	base, queryString, _ := strings.Cut(rawUrl, "?")
+
+	query := rawQuery{base: base, fragment: fragment, hasFragment: hasFragment}
+	for _, pair := range strings.Split(queryString, "&") {
+		if pair != "" {
+			query.pairs = append(query.pairs, pair)
+		}
+	}
+
+	return query
+}
+
+func (q rawQuery) build(pairs []string, separator string) string {
+	newUrl := q.base + "?" + strings.Join(pairs, separator)
+	if q.hasFragment {
+		newUrl += "#" + q.fragment
+	}
+	return newUrl
+}
+
+// CombineUrlPathWithPayload puts the payload in each path segment in turn and as a new trailing segment,
+// the segment index is used as the parameter name
+func (us *UrlService) CombineUrlPathWithPayload(rawUrl string, payloads []string) []Injection {
+	combinedUrls := []Injection{}
+
+	// an explicit marker already says where the payload goes
+	if strings.Contains(rawUrl, "{payload}") {
+		return combinedUrls
+	}
+
+	parsedUrl, err := url.Parse(rawUrl)
+	if err != nil || parsedUrl.Host == "" {
+		utils.Log.Error("Invalid URL:", rawUrl)
+		return combinedUrls
+	}
+
+	segments := strings.Split(strings.TrimPrefix(parsedUrl.EscapedPath(), "/"), "/")
+	if len(segments) == 1 && segments[0] == "" {
+		segments = []string{}
+	}
+
+	build := func(newSegments []string) string {
+		clonedUrl := *parsedUrl
+		// opaque keeps the escaped payload exactly as written, String() only adds the query and fragment
+		clonedUrl.Opaque = "//" + parsedUrl.Host + "/" + strings.Join(newSegments, "/")
+		return clonedUrl.String()
+	}
+
+	seen := make(map[string]struct{})
+	add := func(index int, newSegments []string) {
+		newUrl := build(newSegments)
+		if _, exists := seen[newUrl]; exists {
+			return
+		}
+		seen[newUrl] = struct{}{}
+		combinedUrls = append(combinedUrls, Injection{Url: newUrl, Location: "path", Param: strconv.Itoa(index)})
+	}
+
+	for _, payload := range payloads {
+		escapedPayload := url.PathEscape(payload)
+
+		for ix, segment := range segments {
+			if segment == "" {
+				continue
+			}
+			newSegments := append([]string{}, segments...)
+			newSegments[ix] = escapedPayload
+			add(ix, newSegments)
+		}
+
+		trailing := append([]string{}, segments...)
+		if len(trailing) > 0 && trailing[len(trailing)-1] == "" {
+			trailing = trailing[:len(trailing)-1]
+		}
+		add(len(trailing), append(trailing, escapedPayload))
+	}
+
+	return combinedUrls
+}
+
 func (us *UrlService) CombineRequestWithPayload(request map[string]string, payloads string) (map[string]string, error) {
 	val, ok := request["_ BODY"]
 	if !ok {
@@ -76,53 +418,281 @@ func (us *UrlService) CombineRequestWithPayload(request map[string]string, paylo
 	return nil, fmt.Errorf("payload not found in request body")
 }
 
+// CombineRequestQueryWithPayload injects every query parameter in the path of a request, one at a time. The
+// payloads are raw and always query escaped so they can't split the pair they go in.
+func (us *UrlService) CombineRequestQueryWithPayload(request map[string]string, payloads []string) []map[string]string {
+	var combinedRequests []map[string]string
+
+	path := request["_ PATH"]
+	if !strings.Contains(path, "?") || strings.Contains(path, "{payload}") {
+		return combinedRequests
+	}
+
+	query := us.splitQuery(path)
+	for ix, pair := range query.pairs {
+		name, _, _ := strings.Cut(pair, "=")
+		for _, payload := range payloads {
+			newPairs := append([]string{}, query.pairs...)
+			newPairs[ix] = name + "=" + url.QueryEscape(payload)
+
+			newRequest := utils.CloneMap(request)
+			newRequest["_ PATH"] = query.build(newPairs, "&")
+			newRequest["_ LOCATION"] = "query"
+			newRequest["_ PARAM"] = name
+			combinedRequests = append(combinedRequests, newRequest)
+		}
+	}
+
+	return combinedRequests
+}
+
+// ParseRequest parses a raw HTTP request file into the request map: headers by name, with repeated headers joined
+// by newlines, and the request line, scheme and body under the "_ " meta keys. CRLF and LF files, absolute-form
+// targets, HTTP/2 pseudo headers and chunked bodies are accepted, errors carry the line number.
 func (us *UrlService) ParseRequest(fileContent string) (request map[string]string, _ error) {
+	return us.parseRequest(fileContent, 1)
+}
+
+// parseRequest parses a request starting at the given line of its file
+func (us *UrlService) parseRequest(fileContent string, firstLine int) (request map[string]string, _ error) {
 	request = make(map[string]string)
 	utils.Log.Info("Parsing request file")
-	fileContent = strings.Trim(fileContent, " ")
-	fileContent = strings.Trim(fileContent, "\n")
-	lines := strings.Split(fileContent, "\n")
 
-	emptyLineCount := 0
-	totalLinesParsed := 0
-	for ix, line := range lines {
-		line := strings.Trim(line, " ")
+	lines := strings.SplitAfter(fileContent, "\n")
+	ix := 0
 
-		if len(line) == 0 {
-			emptyLineCount++
-			continue
+	// blank lines before the request are editor noise
+	for ix < len(lines) && strings.TrimSpace(lines[ix]) == "" {
+		ix++
+	}
+	if ix == len(lines) {
+		return nil, fmt.Errorf("empty request file")
+	}
+
+	// exported HTTP/2 requests have no request line, the pseudo headers carry it
+	if requestLine := strings.TrimSpace(lines[ix]); !strings.HasPrefix(requestLine, ":") {
+		if err := us.parseRequestLine(request, requestLine, ix+firstLine); err != nil {
+			return nil, err
 		}
-		totalLinesParsed++
+		ix++
 
-		// parse request line POST /path HTTP/1.1
-		if ix == 0 {
-			lineArr := strings.Split(strings.Trim(line, " "), " ")
-			if len(lineArr) != 3 {
-				utils.Log.Error("Invalid request format")
-				return nil, fmt.Errorf("invalid request format")
-			}
-			request["_ METHOD"] = lineArr[0]
-			request["_ PATH"] = lineArr[1]
-			request["_ PROTOCOL"] = lineArr[2]
+		// older request files have a blank line between the request line and the headers
+		if ix+1 < len(lines) && strings.TrimSpace(lines[ix]) == "" && headerLinePattern.MatchString(lines[ix+1]) {
+			ix++
+		}
+	}
+
+	lastHeader := ""
+	for ; ix < len(lines); ix++ {
+		line := strings.TrimRight(lines[ix], "\r\n")
+		if strings.TrimSpace(line) == "" {
+			ix++
+			break
+		}
 
+		// obsolete line folding continues the previous header
+		if line[0] == ' ' || line[0] == '\t' {
+			if lastHeader == "" {
+				return nil, fmt.Errorf("line %d: continuation line without a header", ix+firstLine)
+			}
+			request[lastHeader] += " " + strings.TrimSpace(line)
 			continue
 		}
 
-		// if we have 2 empty lines already, (one after request line and one after headers), or if we are at the end of the file
-		if emptyLineCount == 2 || (ix == len(lines)-1) {
-			request["_ BODY"] = strings.Join(lines[ix:], "\n")
-			break
+		name, value, err := us.parseHeaderLine(line, ix+firstLine)
+		if err != nil {
+			return nil, err
 		}
 
-		// parse headers
-		headerLine := strings.SplitN(line, ":", 2)
-		if len(headerLine) != 2 {
-			utils.Log.Error("Invalid header format", headerLine)
-			return nil, fmt.Errorf("invalid header format")
+		if strings.HasPrefix(name, ":") {
+			if err := us.parsePseudoHeader(request, name, value, ix+firstLine); err != nil {
+				return nil, err
+			}
+			lastHeader = ""
+			continue
 		}
 
-		request[headerLine[0]] = strings.Trim(headerLine[1], " ")
+		if key, existing := HeaderValue(request, name); key != "" {
+			// the authority of an absolute-form target or :authority wins over the Host header
+			if strings.EqualFold(name, "Host") {
+				lastHeader = ""
+				continue
+			}
+
+			separator := "\n"
+			if strings.EqualFold(name, "Cookie") {
+				separator = "; "
+			}
+			request[key] = existing + separator + value
+			name = key
+		} else {
+			request[name] = value
+		}
+		lastHeader = name
+	}
+
+	bodyLine := ix + firstLine
+	body := strings.Join(lines[min(ix, len(lines)):], "")
+
+	if request["_ METHOD"] == "" || request["_ PATH"] == "" {
+		return nil, fmt.Errorf("line %d: request has no method or path", firstLine)
 	}
 
+	if key, encoding := HeaderValue(request, "Transfer-Encoding"); strings.Contains(strings.ToLower(encoding), "chunked") {
+		decoded, err := us.decodeChunked(body, bodyLine)
+		if err != nil {
+			return nil, err
+		}
+		body = decoded
+		delete(request, key)
+		request["Content-Length"] = strconv.Itoa(len(body))
+	} else {
+		// the line break editors add at the end of the file is not part of the body
+		body = strings.TrimSuffix(strings.TrimSuffix(body, "\n"), "\r")
+	}
+
+	request["_ BODY"] = body
 	return request, nil
 }
+
+// requestDelimiterPattern separates several requests saved in one file, a line of three or more `=`
+var requestDelimiterPattern = regexp.MustCompile(`^={3,}\s*$`)
+
+// ParseRequests parses a file holding one request, or several separated by delimiter lines
+func (us *UrlService) ParseRequests(fileContent string) ([]map[string]string, error) {
+	var requests []map[string]string
+
+	lines := strings.SplitAfter(fileContent, "\n")
+	start := 0
+	for ix := 0; ix <= len(lines); ix++ {
+		if ix < len(lines) && !requestDelimiterPattern.MatchString(strings.TrimRight(lines[ix], "\r\n")) {
+			continue
+		}
+
+		chunk := strings.Join(lines[start:ix], "")
+		if strings.TrimSpace(chunk) != "" {
+			request, err := us.parseRequest(chunk, start+1)
+			if err != nil {
+				return nil, err
+			}
+			requests = append(requests, request)
+		}
+		start = ix + 1
+	}
+
+	return requests, nil
+}
+
+// headerLinePattern matches a header line, or a pseudo header of an exported HTTP/2 request
+var headerLinePattern = regexp.MustCompile("^:?[!#$%&'*+\\-.^_`|~0-9A-Za-z]+:")
+
+// parseRequestLine reads `METHOD target [version]`, an absolute-form target also sets the scheme and Host
+func (us *UrlService) parseRequestLine(request map[string]string, line string, lineNumber int) error {
+	tokens := strings.Fields(line)
+	if len(tokens) < 2 {
+		return fmt.Errorf("line %d: invalid request line %q, expected METHOD target HTTP/version", lineNumber, line)
+	}
+
+	request["_ METHOD"] = strings.ToUpper(tokens[0])
+	request["_ PROTOCOL"] = "HTTP/1.1"
+
+	targetTokens := tokens[1:]
+	if last := tokens[len(tokens)-1]; len(tokens) > 2 && strings.HasPrefix(strings.ToUpper(last), "HTTP/") {
+		request["_ PROTOCOL"] = last
+		targetTokens = tokens[1 : len(tokens)-1]
+	}
+
+	// unescaped spaces in the target are kept as part of it
+	return us.setTarget(request, strings.Join(targetTokens, "%20"), lineNumber)
+}
+
+// setTarget sets the path of an origin-form target, or the scheme, Host and path of an absolute-form one
+func (us *UrlService) setTarget(request map[string]string, target string, lineNumber int) error {
+	if !strings.Contains(target, "://") {
+		request["_ PATH"] = target
+		return nil
+	}
+
+	parsedUrl, err := url.Parse(target)
+	if err != nil || parsedUrl.Host == "" {
+		return fmt.Errorf("line %d: invalid absolute request target %q", lineNumber, target)
+	}
+
+	request["_ SCHEME"] = parsedUrl.Scheme
+	request["_ PATH"] = parsedUrl.RequestURI()
+	if key, _ := HeaderValue(request, "Host"); key == "" {
+		request["Host"] = parsedUrl.Host
+	}
+	return nil
+}
+
+// parseHeaderLine splits `Name: value`, keeping the leading colon of pseudo headers in the name
+func (us *UrlService) parseHeaderLine(line string, lineNumber int) (string, string, error) {
+	if !headerLinePattern.MatchString(line) {
+		return "", "", fmt.Errorf("line %d: invalid header %q, expected Name: value", lineNumber, line)
+	}
+
+	separator := strings.Index(line[1:], ":") + 1
+	return line[:separator], strings.TrimSpace(line[separator+1:]), nil
+}
+
+// parsePseudoHeader maps the HTTP/2 pseudo headers onto the request line and Host
+func (us *UrlService) parsePseudoHeader(request map[string]string, name string, value string, lineNumber int) error {
+	switch strings.ToLower(name) {
+	case ":method":
+		request["_ METHOD"] = strings.ToUpper(value)
+		request["_ PROTOCOL"] = "HTTP/2"
+	case ":path":
+		return us.setTarget(request, value, lineNumber)
+	case ":scheme":
+		request["_ SCHEME"] = value
+	case ":authority":
+		if key, _ := HeaderValue(request, "Host"); key == "" {
+			request["Host"] = value
+		}
+	default:
+		return fmt.Errorf("line %d: unknown pseudo header %q", lineNumber, name)
+	}
+	return nil
+}
+
+// decodeChunked decodes a chunked body, trailers after the last chunk are dropped
+func (us *UrlService) decodeChunked(body string, firstLine int) (string, error) {
+	var decoded strings.Builder
+	lineNumber := firstLine
+
+	for {
+		sizeLine, rest, found := strings.Cut(body, "\n")
+		if !found && strings.TrimSpace(sizeLine) == "" {
+			return "", fmt.Errorf("line %d: chunked body ends without the last chunk", lineNumber)
+		}
+
+		// chunk extensions after ; are ignored
+		sizeText, _, _ := strings.Cut(strings.TrimSpace(sizeLine), ";")
+		size, err := strconv.ParseInt(strings.TrimSpace(sizeText), 16, 64)
+		if err != nil || size < 0 {
+			return "", fmt.Errorf("line %d: invalid chunk size %q", lineNumber, strings.TrimSpace(sizeLine))
+		}
+		lineNumber++
+
+		if size == 0 {
+			return decoded.String(), nil
+		}
+
+		if int64(len(rest)) < size {
+			return "", fmt.Errorf("line %d: chunk of %d bytes is longer than the remaining body", lineNumber, size)
+		}
+
+		chunk := rest[:size]
+		decoded.WriteString(chunk)
+		lineNumber += strings.Count(chunk, "\n")
+
+		rest = rest[size:]
+		rest = strings.TrimPrefix(rest, "\r")
+		if !strings.HasPrefix(rest, "\n") {
+			return "", fmt.Errorf("line %d: missing line break after chunk", lineNumber)
+		}
+		body = rest[1:]
+		lineNumber++
+	}
+}
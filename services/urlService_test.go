@@ -0,0 +1,310 @@
+package services
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseRequest(t *testing.T) {
+	us, err := GetUrlService()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		content string
+		want    map[string]string
+	}{
+		{
+			name:    "CRLF line endings",
+			content: "POST /a?x=1 HTTP/1.1\r\nHost: example.com\r\nContent-Type: application/x-www-form-urlencoded\r\n\r\na=1&b=2",
+			want: map[string]string{
+				"_ METHOD": "POST", "_ PATH": "/a?x=1", "_ PROTOCOL": "HTTP/1.1",
+				"Host": "example.com", "Content-Type": "application/x-www-form-urlencoded", "_ BODY": "a=1&b=2",
+			},
+		},
+		{
+			name:    "LF line endings, leading blank lines and the final newline",
+			content: "\n\nPOST / HTTP/1.1\nHost: example.com\n\nline one\nline two\n",
+			want: map[string]string{
+				"_ METHOD": "POST", "_ PATH": "/", "_ PROTOCOL": "HTTP/1.1", "Host": "example.com", "_ BODY": "line one\nline two",
+			},
+		},
+		{
+			name:    "absolute-form target wins over the Host header",
+			content: "GET https://example.com:8443/p?q=1 HTTP/1.1\nHost: other.example.com\n\n",
+			want: map[string]string{
+				"_ METHOD": "GET", "_ PATH": "/p?q=1", "_ PROTOCOL": "HTTP/1.1", "_ SCHEME": "https",
+				"Host": "example.com:8443", "_ BODY": "",
+			},
+		},
+		{
+			name:    "HTTP/2 pseudo headers",
+			content: ":method: post\n:scheme: https\n:authority: example.com\n:path: /api\ncontent-type: application/json\n\n{}",
+			want: map[string]string{
+				"_ METHOD": "POST", "_ PATH": "/api", "_ PROTOCOL": "HTTP/2", "_ SCHEME": "https",
+				"Host": "example.com", "content-type": "application/json", "_ BODY": "{}",
+			},
+		},
+		{
+			name:    "repeated and folded headers",
+			content: "GET / HTTP/1.1\nHost: example.com\nAccept: text/html\naccept: */*\nCookie: a=1\ncookie: b=2\nX-Long: one\n\ttwo\n\n",
+			want: map[string]string{
+				"_ METHOD": "GET", "_ PATH": "/", "_ PROTOCOL": "HTTP/1.1", "Host": "example.com",
+				"Accept": "text/html\n*/*", "Cookie": "a=1; b=2", "X-Long": "one two", "_ BODY": "",
+			},
+		},
+		{
+			name:    "chunked body with an extension and trailers",
+			content: "POST / HTTP/1.1\r\nHost: example.com\r\nTransfer-Encoding: chunked\r\n\r\n5\r\nhello\r\n6;ext=1\r\n world\r\n0\r\nTrailer: x\r\n\r\n",
+			want: map[string]string{
+				"_ METHOD": "POST", "_ PATH": "/", "_ PROTOCOL": "HTTP/1.1", "Host": "example.com",
+				"Content-Length": "11", "_ BODY": "hello world",
+			},
+		},
+		{
+			name:    "blank line after the request line, no version and a space in the target",
+			content: "get /a b\n\nHost: example.com\n\n",
+			want: map[string]string{
+				"_ METHOD": "GET", "_ PATH": "/a%20b", "_ PROTOCOL": "HTTP/1.1", "Host": "example.com", "_ BODY": "",
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := us.ParseRequest(test.content)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("ParseRequest() =\n%q\nwant\n%q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestParseRequestErrors(t *testing.T) {
+	us, err := GetUrlService()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		content string
+		want    string
+	}{
+		{"\n \n", "empty request file"},
+		{"GET\nHost: example.com\n\n", "line 1: invalid request line"},
+		{"\nGET / HTTP/1.1\nHost: example.com\nnot a header\n\n", "line 4: invalid header"},
+		{"GET / HTTP/1.1\n folded\n\n", "line 2: continuation line without a header"},
+		{":method: GET\n:status: 200\n\n", "line 2: unknown pseudo header"},
+		{":authority: example.com\n\n", "line 1: request has no method or path"},
+		{"GET http://[::1 HTTP/1.1\n\n", "line 1: invalid absolute request target"},
+		{"POST / HTTP/1.1\nTransfer-Encoding: chunked\n\nzz\n", "line 4: invalid chunk size"},
+		{"POST / HTTP/1.1\nTransfer-Encoding: chunked\n\n3\nabc\nA\nshort\n", "line 7: chunk of 10 bytes"},
+		{"POST / HTTP/1.1\nTransfer-Encoding: chunked\n\n3\nabcdef\n", "line 5: missing line break after chunk"},
+		{"POST / HTTP/1.1\nTransfer-Encoding: chunked\n\n3\nabc\n", "line 6: chunked body ends without the last chunk"},
+	}
+
+	for _, test := range tests {
+		_, err := us.ParseRequest(test.content)
+		if err == nil || !strings.Contains(err.Error(), test.want) {
+			t.Errorf("ParseRequest(%q) error = %v, want %q", test.content, err, test.want)
+		}
+	}
+}
+
+func TestParseRequests(t *testing.T) {
+	us, err := GetUrlService()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	content := "GET /a HTTP/1.1\nHost: example.com\n\n===\n\nPOST /b HTTP/1.1\r\nHost: example.com\r\n\r\nx=1\r\n=====\r\n\n"
+	requests, err := us.ParseRequests(content)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(requests) != 2 {
+		t.Fatalf("ParseRequests() returned %d requests, want 2", len(requests))
+	}
+	if requests[0]["_ PATH"] != "/a" || requests[1]["_ PATH"] != "/b" || requests[1]["_ BODY"] != "x=1" {
+		t.Errorf("ParseRequests() = %q", requests)
+	}
+
+	// line numbers count from the start of the file, not of the request
+	_, err = us.ParseRequests("GET /a HTTP/1.1\nHost: example.com\n\n===\nGET /b HTTP/1.1\nnot a header\n")
+	if err == nil || !strings.Contains(err.Error(), "line 6: invalid header") {
+		t.Errorf("ParseRequests() error = %v, want line 6", err)
+	}
+}
+
+func TestCombineRequestQueryWithPayload(t *testing.T) {
+	us, err := GetUrlService()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	request := map[string]string{"_ METHOD": "POST", "_ PATH": "/api/items?id=1&view=full#top", "_ BODY": "a=1"}
+	got := us.CombineRequestQueryWithPayload(request, []string{"<x>", "a&b=c"})
+
+	want := []struct{ path, param string }{
+		{"/api/items?id=%3Cx%3E&view=full#top", "id"},
+		{"/api/items?id=a%26b%3Dc&view=full#top", "id"},
+		{"/api/items?id=1&view=%3Cx%3E#top", "view"},
+		{"/api/items?id=1&view=a%26b%3Dc#top", "view"},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("CombineRequestQueryWithPayload() returned %d requests, want %d", len(got), len(want))
+	}
+	for ix, request := range got {
+		if request["_ PATH"] != want[ix].path || request["_ PARAM"] != want[ix].param || request["_ LOCATION"] != "query" || request["_ BODY"] != "a=1" {
+			t.Errorf("request %d = %q, want path %s param %s", ix, request, want[ix].path, want[ix].param)
+		}
+	}
+
+	if got := us.CombineRequestQueryWithPayload(map[string]string{"_ PATH": "/api/items"}, []string{"<x>"}); len(got) != 0 {
+		t.Errorf("CombineRequestQueryWithPayload() without a query = %q", got)
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	us, err := GetUrlService()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		url  string
+		want string
+	}{
+		{"HTTPS://Example.COM:443/Path/?b=2&a=1#frag", "https://example.com/Path?a=1&b=2"},
+		{"http://example.com:80", "http://example.com/"},
+		{"http://example.com:8080/", "http://example.com:8080/"},
+		{"https://example.com:80/", "https://example.com:80/"},
+		{"https://User@Example.com/", "https://User@example.com/"},
+		{"https://example.com/a%2fb?x=%2f", "https://example.com/a%2Fb?x=%2F"},
+		{"https://example.com/?&&a=1&", "https://example.com/?a=1"},
+		{"https://example.com/?b=1&a=2&a=1", "https://example.com/?a=2&a=1&b=1"},
+		{"https://example.com/#/route", "https://example.com/#/route"},
+		{"https://example.com/#!/route", "https://example.com/#!/route"},
+		{"https://example.com/#q={payload}", "https://example.com/#q={payload}"},
+		{"not a url", "not a url"},
+	}
+
+	for _, test := range tests {
+		if got := us.Normalize(test.url); got != test.want {
+			t.Errorf("Normalize(%s) = %s, want %s", test.url, got, test.want)
+		}
+	}
+}
+
+func TestNormalizeDistinct(t *testing.T) {
+	us, err := GetUrlService()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		a, b string
+		same bool
+	}{
+		{"https://example.com/a?x=1&y=2", "https://EXAMPLE.com:443/a/?y=2&x=1#top", true},
+		{"https://example.com/a?x=%2f", "https://example.com/a?x=%2F", true},
+		{"https://example.com/Item", "https://example.com/item", false},
+		{"http://example.com/", "https://example.com/", false},
+		{"https://example.com/", "https://example.com:8443/", false},
+		{"https://example.com/?a=1", "https://example.com/?a=2", false},
+		{"https://example.com/?a=1&a=2", "https://example.com/?a=2&a=1", false},
+		{"https://example.com/#/a", "https://example.com/#/b", false},
+		{"https://example.com/a%2Fb", "https://example.com/a/b", false},
+	}
+
+	for _, test := range tests {
+		if same := us.Normalize(test.a) == us.Normalize(test.b); same != test.same {
+			t.Errorf("Normalize(%s) == Normalize(%s) is %t, want %t", test.a, test.b, same, test.same)
+		}
+	}
+}
+
+func TestShape(t *testing.T) {
+	us, err := GetUrlService()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		url  string
+		want string
+	}{
+		{"https://example.com/", "https://example.com/?"},
+		{"https://example.com/product/123?ref=a", "https://example.com/product/{int}?ref"},
+		{"https://Example.com/product/456/?ref=b#/tab/7", "https://example.com/product/{int}?ref"},
+		{"https://example.com/p/123.html?b=1&a=2&a=3", "https://example.com/p/{int}.html?a&b"},
+		{"https://example.com/u/550E8400-e29b-41d4-a716-446655440000/edit", "https://example.com/u/{uuid}/edit?"},
+		{"https://example.com/blob/3f2a9c1d7e/raw", "https://example.com/blob/{hex}/raw?"},
+		{"https://example.com/s/order_1234567890ab", "https://example.com/s/{id}?"},
+		{"https://example.com/v2/cafe1/2024-01-15", "https://example.com/v2/cafe1/2024-01-15?"},
+	}
+
+	for _, test := range tests {
+		if got := us.Shape(test.url); got != test.want {
+			t.Errorf("Shape(%s) = %s, want %s", test.url, got, test.want)
+		}
+	}
+
+	distinct := [][2]string{
+		{"https://example.com/product/123?ref=a", "https://example.com/product/123?other=a"},
+		{"https://example.com/product/123", "https://example.com/item/123"},
+		{"https://example.com/product/123", "https://shop.example.com/product/123"},
+		{"https://example.com/p/123.html", "https://example.com/p/123.json"},
+		{"https://example.com/p/123", "https://example.com/p/123/edit"},
+		{"https://example.com/v1/list", "https://example.com/v2/list"},
+	}
+	for _, pair := range distinct {
+		if us.Shape(pair[0]) == us.Shape(pair[1]) {
+			t.Errorf("Shape(%s) and Shape(%s) are both %s", pair[0], pair[1], us.Shape(pair[0]))
+		}
+	}
+}
+
+func TestShapeSegment(t *testing.T) {
+	us, err := GetUrlService()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		segment string
+		want    string
+	}{
+		{"123", "{int}"},
+		{"0", "{int}"},
+		{"123.html", "{int}.html"},
+		{"550e8400-e29b-41d4-a716-446655440000", "{uuid}"},
+		{"550e8400-e29b-41d4-a716-446655440000.json", "{uuid}.json"},
+		{"deadbeef1", "{hex}"},
+		{"DEADBEEF12", "{hex}"},
+		{"order_1234567890ab", "{id}"},
+		{"a1b2c3d4-e5f6-g7h8", "{id}"},
+		// short hex and word like segments are names, not identifiers
+		{"cafe1", "cafe1"},
+		{"v2", "v2"},
+		{"deadbeef", "deadbeef"},
+		{"user_12345", "user_12345"},
+		{"2024-01-15", "2024-01-15"},
+		{"products", "products"},
+		{".htaccess", ".htaccess"},
+		{"1.2.3", "1.2.3"},
+		{"", ""},
+	}
+
+	for _, test := range tests {
+		if got := us.shapeSegment(test.segment); got != test.want {
+			t.Errorf("shapeSegment(%s) = %s, want %s", test.segment, got, test.want)
+		}
+	}
+}
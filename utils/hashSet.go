@@ -0,0 +1,64 @@
+package utils
+
+import "hash/fnv"
+
+// HashSet is a compact set of strings keeping only their 64-bit FNV-1a hash in an open addressing table.
+// The table doubles at 3/4 full, so an entry takes about 10.7 to 21 bytes, and the old table is held as well
+// while growing. Two different strings sharing a hash is unlikely enough for deduplicating targets.
+type HashSet struct {
+	slots []uint64
+	count int
+}
+
+func NewHashSet() *HashSet {
+	return &HashSet{slots: make([]uint64, 1024)}
+}
+
+// Add adds the string and reports whether it was not in the set yet
+func (hs *HashSet) Add(value string) bool {
+	hash := fnv.New64a()
+	hash.Write([]byte(value))
+	sum := hash.Sum64()
+	if sum == 0 {
+		// 0 marks an empty slot
+		sum = 1
+	}
+
+	if !hs.insert(sum) {
+		return false
+	}
+
+	hs.count++
+	if hs.count*4 > len(hs.slots)*3 {
+		hs.grow()
+	}
+	return true
+}
+
+// Len returns the number of strings in the set
+func (hs *HashSet) Len() int {
+	return hs.count
+}
+
+func (hs *HashSet) insert(sum uint64) bool {
+	mask := uint64(len(hs.slots) - 1)
+	for ix := sum & mask; ; ix = (ix + 1) & mask {
+		switch hs.slots[ix] {
+		case 0:
+			hs.slots[ix] = sum
+			return true
+		case sum:
+			return false
+		}
+	}
+}
+
+func (hs *HashSet) grow() {
+	old := hs.slots
+	hs.slots = make([]uint64, len(old)*2)
+	for _, sum := range old {
+		if sum != 0 {
+			hs.insert(sum)
+		}
+	}
+}
@@ -0,0 +1,47 @@
+package utils
+
+import (
+	"strconv"
+	"testing"
+)
+
+func TestHashSet(t *testing.T) {
+	hs := NewHashSet()
+
+	if !hs.Add("a") || !hs.Add("b") || !hs.Add("") {
+		t.Error("Add() of a new string returned false")
+	}
+	if hs.Add("a") || hs.Add("") {
+		t.Error("Add() of a string already in the set returned true")
+	}
+	if hs.Len() != 3 {
+		t.Errorf("Len() = %d, want 3", hs.Len())
+	}
+}
+
+func TestHashSetGrow(t *testing.T) {
+	hs := NewHashSet()
+
+	// well past the 768 entries the first table holds
+	for ix := 0; ix < 5000; ix++ {
+		if !hs.Add("https://example.com/?id=" + strconv.Itoa(ix)) {
+			t.Fatalf("Add() of entry %d returned false", ix)
+		}
+	}
+	if hs.Len() != 5000 {
+		t.Errorf("Len() = %d, want 5000", hs.Len())
+	}
+	if len(hs.slots) != 8192 {
+		t.Errorf("table has %d slots, want 8192", len(hs.slots))
+	}
+
+	// every entry is still found after the table grew
+	for ix := 0; ix < 5000; ix++ {
+		if hs.Add("https://example.com/?id=" + strconv.Itoa(ix)) {
+			t.Fatalf("Add() of entry %d returned true after growing", ix)
+		}
+	}
+	if hs.Len() != 5000 {
+		t.Errorf("Len() = %d after adding duplicates, want 5000", hs.Len())
+	}
+}
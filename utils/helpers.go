@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 )
 
@@ -52,6 +53,20 @@ func HandleCtrlC(cancel context.CancelFunc) {
 	}()
 }
 
+// ReplaceInMap returns a copy of the map with old replaced by new in every value
+func ReplaceInMap(original map[string]string, old string, new string) map[string]string {
+	if original == nil {
+		return nil
+	}
+
+	replaced := make(map[string]string, len(original))
+	for key, value := range original {
+		replaced[key] = strings.ReplaceAll(value, old, new)
+	}
+
+	return replaced
+}
+
 func CloneMap(original map[string]string) map[string]string {
 	// Create a new map with the same type as the original
 	cloned := make(map[string]string, len(original))